@@ -0,0 +1,98 @@
+package ginqq
+
+import (
+	"regexp"
+	"strings"
+)
+
+const xApiStandardChecked = "ginqq.api_standard_checked"
+
+// idPattern Transaction-ID、Trace-ID 格式：32 位十六进制字符，与 W3C trace-id 一致。
+var idPattern = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+
+// ApiStandardViolation 不符合部门 API 规范的请求头。
+type ApiStandardViolation struct {
+	Header string `json:"header"`
+	Reason string `json:"reason"` // missing、invalid、mismatch
+	Value  string `json:"value,omitempty"`
+}
+
+func (v ApiStandardViolation) String() string {
+	if v.Value == "" {
+		return v.Header + " " + v.Reason
+	}
+	return v.Header + " " + v.Reason + " " + `"` + v.Value + `"`
+}
+
+// apiStandardChecker 服务端 API 规范校验：
+//   - Method-Code 必填，配置了 ApiStandardServerConfig.MethodCodePattern 时需符合格式，且与路由声明的 MethodCode 一致
+//   - FCode（User-Agent）必填，需为合法的服务编码
+//   - Transaction-ID、Trace-ID 缺失时生成并补全到请求头，已传入时需为 32 位十六进制字符
+type apiStandardChecker struct {
+	cfg       *ApiStandardServerConfig
+	skipPaths map[string]bool
+}
+
+func newApiStandardChecker(cfg *ApiStandardServerConfig) *apiStandardChecker {
+	a := &apiStandardChecker{cfg: cfg, skipPaths: make(map[string]bool, len(cfg.SkipPaths))}
+	for _, path := range cfg.SkipPaths {
+		a.skipPaths[path] = true
+	}
+	return a
+}
+
+func (a *apiStandardChecker) handle(c *Context) {
+	if a.skipPaths[c.Request.URL.Path] {
+		c.Next()
+		return
+	}
+	c.Set(xApiStandardChecked, true)
+	if violations := a.check(c); len(violations) > 0 && a.reject(c, violations) {
+		return
+	}
+	c.Next()
+}
+
+func (a *apiStandardChecker) check(c *Context) []ApiStandardViolation {
+	var violations []ApiStandardViolation
+	header := c.Request.Header
+
+	if methodCode := strings.TrimSpace(header.Get(XMethodCode)); methodCode == "" {
+		violations = append(violations, ApiStandardViolation{Header: XMethodCode, Reason: "missing"})
+	} else if a.cfg.methodCodePattern != nil && !a.cfg.methodCodePattern.MatchString(strings.ToUpper(methodCode)) {
+		violations = append(violations, ApiStandardViolation{Header: XMethodCode, Reason: "invalid", Value: methodCode})
+	}
+
+	if fcode := strings.TrimSpace(header.Get(XFCode)); fcode == "" {
+		violations = append(violations, ApiStandardViolation{Header: XFCode, Reason: "missing"})
+	} else if !svcCodePattern.MatchString(strings.ToUpper(fcode)) {
+		violations = append(violations, ApiStandardViolation{Header: XFCode, Reason: "invalid", Value: fcode})
+	}
+
+	if id := header.Get(XTransactionID); id == "" {
+		header.Set(XTransactionID, c.GetTransactionID())
+	} else if !idPattern.MatchString(id) {
+		violations = append(violations, ApiStandardViolation{Header: XTransactionID, Reason: "invalid", Value: id})
+	}
+	if id := header.Get(XTraceID); id == "" {
+		header.Set(XTraceID, c.GetTraceID()) // 优先使用 traceparent 中的 trace-id
+	} else if !idPattern.MatchString(id) {
+		violations = append(violations, ApiStandardViolation{Header: XTraceID, Reason: "invalid", Value: id})
+	}
+	return violations
+}
+
+// reject 记录不合规调用，Enforce 模式下以标准响应格式拒绝请求并返回 true 。
+func (a *apiStandardChecker) reject(c *Context, violations []ApiStandardViolation) bool {
+	reasons := make([]string, len(violations))
+	for i, v := range violations {
+		reasons[i] = v.String()
+	}
+	detail := strings.Join(reasons, "; ")
+	c.Logger().WithField("enforce", a.cfg.Enforce).Warn("request does not conform to API standard: " + detail)
+	if !a.cfg.Enforce {
+		return false
+	}
+	c.fail(ErrApiStandardViolation, detail, violations)
+	return true
+}
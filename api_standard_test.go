@@ -0,0 +1,94 @@
+package ginqq
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApiStandardServerMiddleware(t *testing.T) {
+	newEngine := func(cfg *ApiStandardServerConfig) *GinQQ {
+		g := newTestGinQQ(t, &Config{ApiStandardServerConfig: cfg}, func([]byte) {})
+		g.Use(ApiStandardServerMiddleware())
+		g.GET("/orders", MethodCode("I00101"), func(c *Context) {
+			c.String(http.StatusOK, c.GetHeader(XTransactionID)+","+c.GetHeader(XTraceID))
+		})
+		g.GET("/health", func(c *Context) { c.Status(http.StatusOK) })
+		return g
+	}
+	g := newEngine(&ApiStandardServerConfig{Enforce: true, MethodCodePattern: `^[A-Z]\d{5}$`, SkipPaths: []string{"/health"}})
+
+	for _, tt := range []struct {
+		name       string
+		path       string
+		headers    map[string]string
+		violations []string
+	}{
+		{name: "valid", path: "/orders", headers: map[string]string{XMethodCode: "i00101", XFCode: "b186010101"}},
+		{name: "missing", path: "/orders", violations: []string{"Method-Code missing", "User-Agent missing"}},
+		{name: "invalid", path: "/orders", headers: map[string]string{
+			XMethodCode: "order", XFCode: "curl/8.0", XTransactionID: "1", XTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		}, violations: []string{`Method-Code invalid "order"`, `User-Agent invalid "curl/8.0"`, `Transaction-ID invalid "1"`}},
+		{name: "mismatch", path: "/orders", headers: map[string]string{XMethodCode: "I00102", XFCode: "B186010101"},
+			violations: []string{`Method-Code mismatch "I00102"`}},
+		{name: "skipped", path: "/health"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			g.ServeHTTP(w, req)
+
+			if len(tt.violations) == 0 {
+				if w.Code != http.StatusOK {
+					t.Fatalf("status %d: %s", w.Code, w.Body)
+				}
+				if tt.path == "/orders" {
+					ids := strings.Split(w.Body.String(), ",")
+					if !idPattern.MatchString(ids[0]) || !idPattern.MatchString(ids[1]) {
+						t.Errorf("Transaction-ID/Trace-ID not filled: %q", w.Body)
+					}
+				}
+				return
+			}
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status %d, want 400", w.Code)
+			}
+			var resp struct {
+				Code string                 `json:"code"`
+				Msg  string                 `json:"msg"`
+				Data []ApiStandardViolation `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Code != CodeApiStandardViolation || len(resp.Data) != len(tt.violations) {
+				t.Fatalf("unexpected response: %s", w.Body)
+			}
+			for i, want := range tt.violations {
+				if got := resp.Data[i].String(); got != want {
+					t.Errorf("violation %d = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+
+	// 默认只记录不拦截
+	reportOnly := newEngine(nil)
+	w := httptest.NewRecorder()
+	reportOnly.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("report-only mode rejected request: %d", w.Code)
+	}
+
+	if _, err := newGinQQ(&Config{
+		SvcCode: "A186010101", AppName: "ginqq_test", DisableProgramLog: true,
+		ApiStandardServerConfig: &ApiStandardServerConfig{MethodCodePattern: "["},
+	}); err == nil || !strings.Contains(err.Error(), "MethodCodePattern") {
+		t.Errorf("expected MethodCodePattern error, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package ginqq
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy 异步写入队列已满时的处理策略。
+type OverflowPolicy int
+
+const (
+	OverflowDefault    OverflowPolicy = iota // 使用默认策略：流水日志 OverflowBlock，程序日志 OverflowDropOldest
+	OverflowBlock                            // 阻塞等待队列空闲，保证日志不丢失
+	OverflowDropNewest                       // 丢弃当前写入的日志
+	OverflowDropOldest                       // 丢弃队列中最早的日志，写入当前日志
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDefault:
+		return "default"
+	case OverflowBlock:
+		return "block"
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+}
+
+// UnmarshalText 解析配置文件、环境变量中的策略名称：default、block、drop-newest、drop-oldest 。
+func (p *OverflowPolicy) UnmarshalText(text []byte) error {
+	switch strings.ReplaceAll(strings.ToLower(string(text)), "_", "-") {
+	case "default":
+		*p = OverflowDefault
+	case "block":
+		*p = OverflowBlock
+	case "drop-newest":
+		*p = OverflowDropNewest
+	case "drop-oldest":
+		*p = OverflowDropOldest
+	default:
+		return fmt.Errorf("unknown overflow policy %q", text)
+	}
+	return nil
+}
+
+// asyncWriter 有界队列 + 固定数量写入协程，日志写入不阻塞请求处理（OverflowBlock 策略除外）。
+type asyncWriter struct {
+	write   func([]byte)
+	queue   chan []byte
+	policy  OverflowPolicy
+	dropped atomic.Uint64
+
+	mu     sync.RWMutex // 保护 closed，避免向已关闭的队列写入
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newAsyncWriter(write func([]byte), queueSize, workers int, policy OverflowPolicy) *asyncWriter {
+	w := &asyncWriter{
+		write:  write,
+		queue:  make(chan []byte, queueSize),
+		policy: policy,
+	}
+	w.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go w.run()
+	}
+	return w
+}
+
+func (w *asyncWriter) run() {
+	defer w.wg.Done()
+	for msg := range w.queue {
+		w.writeOne(msg)
+	}
+}
+
+func (w *asyncWriter) writeOne(msg []byte) {
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Printf("[AsyncWriter] write panic: %v\n", err)
+			debug.PrintStack()
+		}
+	}()
+	w.write(msg)
+}
+
+// Write 提交一条日志，队列已满时按 OverflowPolicy 处理，关闭后提交的日志计入丢弃数。
+func (w *asyncWriter) Write(msg []byte) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		w.dropped.Add(1)
+		return
+	}
+
+	switch w.policy {
+	case OverflowDropNewest:
+		select {
+		case w.queue <- msg:
+		default:
+			w.dropped.Add(1)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case w.queue <- msg:
+				return
+			default:
+			}
+			select {
+			case <-w.queue:
+				w.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		w.queue <- msg
+	}
+}
+
+// Dropped 返回因队列已满或已关闭而丢弃的日志数。
+func (w *asyncWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Close 停止接收新日志，等待队列中的日志全部写入。
+func (w *asyncWriter) Close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+	w.wg.Wait()
+}
@@ -5,20 +5,15 @@ import (
 	"fmt"
 	lumberjack "github.com/DeRuina/timberjack"
 	"github.com/sirupsen/logrus"
-	"io"
 	"net/http"
+	"regexp"
 	"runtime"
 	"strings"
 	"time"
 )
 
-var (
-	cnf    *Config
-	logger *logrus.Logger
-)
-
 type Config struct {
-	SvcCode string // 服务编码（大写）
+	SvcCode string // 服务编码（大写），如 A186010101，格式见 ParseServiceIdentity
 	AppName string // 应用名称（小写，以下划线拼接）
 
 	// 功能开关&配置
@@ -26,18 +21,34 @@ type Config struct {
 	DisableMetrics        bool
 	MetricsConfig         *MetricsConfig
 	DisableTracing        bool // 链路
+	TracingConfig         *TracingConfig
 	DisableTransactionLog bool // 内部流水
+	TransactionLogConfig  *TransactionLogConfig
+	DisableMasking        bool // 流水敏感信息脱敏
+	MaskingConfig         *MaskingConfig
 
 	// 服务端API规范化
 	DisableApiStandardServer bool // 服务端API规范调用&校验拦截
+	ApiStandardServerConfig  *ApiStandardServerConfig
+	DisableSecurity          bool // 安全过滤（XSS、SQL注入）
+	SecurityConfig           *SecurityConfig
+	SigningConfig            *SigningConfig // 服务间请求签名，配置后出站请求签名、入站请求验签
+	ErrorRegistry            *ErrorRegistry // 业务错误码注册表，默认 DefaultErrorRegistry，实例创建时检查重复注册
 
 	// Http客户端配置
 	DisableHttpClientEnhance bool // http增强
 	HttpClientEnhanceConfig  *HttpClientEnhanceConfig
 
 	DisableProgramLog bool // 是否禁用程序日志
+	ProgramLogConfig  *ProgramLogConfig
+
+	ShutdownTimeout time.Duration // 优雅退出的最长等待时间，默认 30 秒
+
+	ServerTLSConfig *ServerTLSConfig // 服务端 TLS，配置后 RunWithGracefulShutdown 以 HTTPS 提供服务
 
 	LogConfig *LogConfig
+
+	identity ServiceIdentity // init 时由 SvcCode、AppName 解析
 }
 
 type MetricsConfig struct {
@@ -45,6 +56,103 @@ type MetricsConfig struct {
 	Buckets []float64 // 桶设置，单位毫秒，默认 [100, 200, 500, 1000, 2000, 3000, 5000, 10000]
 	// 自定义标签,key:标签名，value:ctx中获取标签值的方法名，如：ctx.Get("operate_type")
 	CustomLabels map[string]string
+	Path         string // 指标暴露路径，默认 "/metrics"
+}
+
+type TransactionLogConfig struct {
+	QueueSize      int            // 待写入流水队列长度，每个输出（Sinks）另有同样长度的队列，默认 4096
+	Workers        int            // 写入协程数，默认 2
+	OverflowPolicy OverflowPolicy // 队列（包括各输出的队列）已满时的处理策略，默认 OverflowBlock
+
+	RequestCaptureLimit  int      // 请求体最多记录的字节数，超出部分截断，默认 64KB
+	ResponseCaptureLimit int      // 响应体最多记录的字节数，超出部分截断，默认 64KB
+	TextContentTypes     []string // 额外按文本记录的媒体类型，如 "application/x-ndjson"，其他非文本类型只记录类型和大小
+
+	// DisablePayloadCapture 不记录请求体、响应体，只记录查询参数，可通过 GinQQ.LogControlHandler 按 Method-Code 临时开启。
+	DisablePayloadCapture bool
+
+	// Fields 自定义流水字段提取器（如填充 User、Tag、ServiceLine），在内置字段之后按顺序执行，只对内部流水生效。
+	Fields []TransactionLogField
+
+	// Sinks 流水输出目标，流水同时写入每个输出，单个输出失败不影响其他输出。
+	// 默认写入 <LogDir>/<svc>_<app>/<svc>_<app>_info-info.log，
+	// 可选 NewFileSink、NewStdoutSink、NewStderrSink、NewSyslogSink、NewHTTPBatchSink 或自定义实现。
+	Sinks []TransactionLogSink
+}
+
+type ApiStandardServerConfig struct {
+	// Enforce 拦截不合规调用（400 及标准响应格式）。默认只记录不合规调用（程序日志 WARN），不拦截，
+	// 历史调用方完成迁移后再开启。
+	Enforce bool
+
+	// MethodCodePattern Method-Code 格式（正则表达式），按部门 API 规范配置，为空时只校验必填。
+	MethodCodePattern string
+
+	SkipPaths []string // 不校验的请求路径，如健康检查接口 "/health"
+
+	methodCodePattern *regexp.Regexp // init 时由 MethodCodePattern 编译
+}
+
+type SecurityConfig struct {
+	// Enforce 拦截命中规则的请求（403 及标准响应格式）。默认只记录（程序日志 WARN 及流水 security_event），不拦截，
+	// 评估误报并配置 AllowList 后再开启。
+	Enforce bool
+
+	Rules               []SecurityRule // 自定义规则，在内置规则之后匹配
+	DisableBuiltinRules bool           // 不使用内置的 XSS、SQL 注入规则
+	Headers             []string       // 检查的请求头，默认 ["Referer"]
+
+	// AllowList 路由放行的规则，key 为路由（如 "/api/articles/:id"），value 为规则 ID 或分类（xss、sqli），
+	// "*" 表示该路由不做检查，如富文本编辑接口放行 xss 。
+	AllowList map[string][]string
+
+	// BodyLimit 检查的请求体最大字节数，默认 1MB 。超出时无法检查，记录为 body-too-large 规则命中，
+	// 上传等接口可在 AllowList 中放行该规则。
+	BodyLimit int
+
+	rules []SecurityRule // init 时编译
+}
+
+type SigningConfig struct {
+	// Keys 按服务编码（FCode）配置的 HMAC 密钥。出站请求使用本服务编码对应的密钥签名，
+	// 入站请求按调用方 FCode 查找密钥验签，没有对应密钥的调用方被拒绝。
+	Keys map[string]string
+
+	MaxClockSkew  time.Duration // 允许的签名时间偏差，默认 5 分钟
+	NonceStore    NonceStore    // 随机串存储，默认进程内存储，多副本部署建议使用共享存储
+	AllowUnsigned bool          // 放行未签名的请求（不设置 Context.VerifiedFCode），用于调用方迁移
+
+	// MaxSignedBodySize 参与签名的请求体上限（字节），验签前需读取完整请求体计算摘要，
+	// 超出时入站请求以 413 拒绝、出站请求返回错误，默认 10MB 。
+	MaxSignedBodySize int64
+
+	SkipPaths     []string // 不验签的请求路径，如健康检查接口 "/health"
+	DisableSign   bool     // 出站请求不签名，只对入站请求验签
+	DisableVerify bool     // 入站请求不验签，只对出站请求签名
+
+	keys map[string]string // init 时按大写服务编码整理的密钥
+}
+
+type ProgramLogConfig struct {
+	Level string // 最低记录级别：trace、debug、info、warn、error、fatal、panic，默认 trace
+
+	// HookStandardLogger 接管 logrus 标准 logger（logrus.Info 等）：标准 logger 的日志按级别写入程序日志文件，
+	// 不再输出到标准错误，实例关闭时恢复。每个进程只有第一个开启的实例接管。默认不接管，程序日志只通过 GinQQ.Logger 记录。
+	HookStandardLogger bool
+
+	QueueSize int // 每个级别待写入日志队列长度，默认 1024
+	// OverflowPolicy 队列已满时的处理策略，默认 OverflowDropOldest，避免日志写入阻塞请求处理，
+	// 丢弃数由 GinQQ.ProgramLogDropped 及指标 program_log_dropped_total 统计。
+	OverflowPolicy OverflowPolicy
+}
+
+type TracingConfig struct {
+	// Exporter 链路导出器，默认以 JSON Lines 格式写入 <LogDir>/<svc>_<app>/<svc>_<app>_span.log，
+	// 对接链路采集器可使用 NewOTLPHTTPExporter 。
+	Exporter      SpanExporter
+	QueueSize     int           // 待导出 Span 队列长度，队列满时丢弃，默认 2048
+	BatchSize     int           // 单批导出的最大 Span 数，默认 512
+	FlushInterval time.Duration // 导出间隔，默认 5 秒
 }
 
 type LogConfig struct {
@@ -78,193 +186,334 @@ type LogConfig struct {
 }
 
 type HttpClientEnhanceConfig struct {
-	Transport                http.RoundTripper // 基础transport，默认使用http.DefaultTransport，可自定义transport设置连接池参数、超时时间等
-	DisableSkipVerify        bool              // 跳过证书认证，默认跳过 TODO 后续增加证书认证体系
-	DisableApiStandardClient bool              // 客户端API规范调用&校验拦截
-	DisableTransactionLog    bool              // 外部流水
-}
+	Transport http.RoundTripper // 基础transport，默认使用未经增强的http.DefaultTransport，可自定义transport设置连接池参数、超时时间等
 
-func (c *Config) GetPlayCode() string {
-	return c.SvcCode[:4]
+	TLS     *TLSClientConfig            // 出站请求的证书配置，默认使用系统根证书验证服务端证书
+	HostTLS map[string]*TLSClientConfig // 按主机（host 或 host:port）覆盖的证书配置，如访问使用内部 CA 或双向 TLS 的服务
+
+	// Deprecated: 默认已验证服务端证书，无需设置；跳过验证使用 TLS.InsecureSkipVerify（仅限测试环境）。
+	DisableSkipVerify bool
+
+	DisableApiStandardClient bool // 客户端API规范调用&校验拦截
+	DisableTransactionLog    bool // 外部流水
+
+	// ReplaceDefaultTransport 将进程级的 http.DefaultTransport 替换为实例增强后的传输层，会影响进程内所有使用默认传输层的库。
+	// 默认不替换，出站请求使用 GinQQ.HTTPClient 或 Context.HTTPClient 。
+	ReplaceDefaultTransport bool
 }
 
-type PlainFormatter struct{}
+type TLSClientConfig struct {
+	CAFiles    []string // 额外信任的 CA 证书（PEM），追加到系统根证书
+	CertFile   string   // 客户端证书（PEM），用于双向 TLS
+	KeyFile    string   // 客户端证书私钥（PEM）
+	ServerName string   // 验证的服务端名称，默认使用请求主机名
+
+	// Pins 公钥固定，服务端证书链中需有证书的 SubjectPublicKeyInfo SHA-256（base64，可带 "sha256/" 前缀）在列表中。
+	Pins []string
 
-func (f *PlainFormatter) Format(entry *logrus.Entry) ([]byte, error) {
-	return []byte(entry.Message + "\n"), nil
+	InsecureSkipVerify bool          // 跳过证书验证，仅限测试环境
+	ReloadInterval     time.Duration // 证书文件检查间隔，文件变化时重新加载，默认 1 分钟
+
+	store *certStore      // init 时加载
+	pins  map[string]bool // init 时解析
 }
 
-// LevelHook 分发不同级别的日志到不同文件
-type LevelHook struct {
-	writers   map[logrus.Level]io.Writer
-	formatter logrus.Formatter
+type ServerTLSConfig struct {
+	CertFile string // 服务端证书（PEM）
+	KeyFile  string // 服务端证书私钥（PEM）
+
+	// ClientCAFiles 验证客户端证书的 CA（PEM），配置后客户端提供的证书需通过验证，可由 Context.ClientCertIdentity 获取客户端标识。
+	ClientCAFiles     []string
+	RequireClientCert bool // 要求客户端提供证书（双向 TLS）
+
+	ReloadInterval time.Duration // 证书文件检查间隔，文件变化时重新加载，默认 1 分钟
+
+	store *certStore // init 时加载
 }
 
-func (h *LevelHook) Levels() []logrus.Level {
-	levels := make([]logrus.Level, 0, len(h.writers))
-	for level := range h.writers {
-		levels = append(levels, level)
+// defaultLogConfig 返回默认日志配置。
+func defaultLogConfig() *LogConfig {
+	var logDir string
+	if runtime.GOOS == "windows" {
+		logDir = "C:\\BllLogs"
+	} else {
+		logDir = "/app/logs"
 	}
-	return levels
+	return &LogConfig{
+		LogDir:           logDir,
+		MaxSize:          1024,
+		MaxBackups:       7,
+		RotationInterval: time.Hour * 24,
+	}
+}
+
+// outboundTransactionLogEnabled 是否记录外部流水。
+func (c *Config) outboundTransactionLogEnabled() bool {
+	return !c.DisableHttpClientEnhance && c.HttpClientEnhanceConfig != nil && !c.HttpClientEnhanceConfig.DisableTransactionLog
 }
 
-func (h *LevelHook) Fire(entry *logrus.Entry) error {
-	writer, ok := h.writers[entry.Level]
-	if !ok {
-		return nil
+// Identity 返回由 SvcCode、AppName 解析的服务标识。
+func (c *Config) Identity() ServiceIdentity {
+	if c.identity.SvcCode != "" {
+		return c.identity
 	}
-	msg, err := h.formatter.Format(entry)
-	if err != nil {
-		return err
+	id, _ := ParseServiceIdentity(c.SvcCode, c.AppName)
+	return id
+}
+
+// GetPlayCode 返回平台编码，SvcCode 格式不正确时返回空字符串。
+func (c *Config) GetPlayCode() string {
+	return c.Identity().PlatformCode
+}
+
+// logFilename 返回日志文件路径 <LogDir>/<svc>_<app>/[dir/]<svc>_<app>_<suffix>.log 。
+func (c *Config) logFilename(dir, suffix string) string {
+	name := c.Identity().Name()
+	if dir != "" {
+		return fmt.Sprintf("%s/%s/%s/%s_%s.log", c.LogConfig.LogDir, name, dir, name, suffix)
 	}
-	_, err = writer.Write(msg)
-	return err
+	return fmt.Sprintf("%s/%s/%s_%s.log", c.LogConfig.LogDir, name, name, suffix)
 }
 
 // init 初始化默认配置。
 func (c *Config) init() error {
 	var errs []error
-	if cnf != nil {
-		return errors.New("config already initialized")
-	}
 
-	if c.SvcCode == "" {
-		errs = append(errs, errors.New(`parameter "SvcCode" is required`))
-	}
-	if c.AppName == "" {
-		errs = append(errs, errors.New(`parameter "AppName" is required`))
+	id, err := ParseServiceIdentity(c.SvcCode, c.AppName)
+	if err != nil {
+		errs = append(errs, err)
 	}
-
-	c.SvcCode = strings.ToUpper(strings.TrimSpace(c.SvcCode))
-	c.AppName = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(c.AppName), "-", "_"))
+	c.SvcCode, c.AppName, c.identity = id.SvcCode, id.AppName, id
 
 	if !c.DisableMetrics {
 		if c.MetricsConfig == nil {
 			c.MetricsConfig = &MetricsConfig{
 				Buckets: []float64{100, 200, 500, 1000, 2000, 3000, 5000, 10000}, // 默认桶
+				Path:    "/metrics",
 			}
 		} else {
-			if len(c.MetricsConfig.Buckets) == 0 {
-				errs = append(errs, errors.New("MetricsConfig.Buckets cannot be empty"))
-			} else {
-				for i, b := range c.MetricsConfig.Buckets {
-					if b <= 0 {
-						errs = append(errs, fmt.Errorf("MetricsConfig.Buckets[%d] must > 0, got %f", i, b))
-					}
-				}
+			if c.MetricsConfig.Path == "" {
+				c.MetricsConfig.Path = "/metrics"
+			}
+			if c.MetricsConfig.Buckets == nil {
+				c.MetricsConfig.Buckets = []float64{100, 200, 500, 1000, 2000, 3000, 5000, 10000}
+			}
+			if err := c.MetricsConfig.validate(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if !c.DisableApiStandardServer {
+		if c.ApiStandardServerConfig == nil {
+			c.ApiStandardServerConfig = &ApiStandardServerConfig{}
+		}
+		if c.ApiStandardServerConfig.MethodCodePattern != "" {
+			pattern, err := regexp.Compile(c.ApiStandardServerConfig.MethodCodePattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("ApiStandardServerConfig.MethodCodePattern: %w", err))
 			}
+			c.ApiStandardServerConfig.methodCodePattern = pattern
 		}
 	}
 
+	if !c.DisableSecurity {
+		if c.SecurityConfig == nil {
+			c.SecurityConfig = &SecurityConfig{}
+		}
+		if c.SecurityConfig.Headers == nil {
+			c.SecurityConfig.Headers = []string{"Referer"}
+		}
+		if c.SecurityConfig.BodyLimit <= 0 {
+			c.SecurityConfig.BodyLimit = defaultSecurityBodyLimit
+		}
+		rules, err := compileSecurityRules(c.SecurityConfig)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		c.SecurityConfig.rules = rules
+	}
+
+	if c.SigningConfig != nil {
+		if c.SigningConfig.MaxClockSkew <= 0 {
+			c.SigningConfig.MaxClockSkew = 5 * time.Minute
+		}
+		if c.SigningConfig.NonceStore == nil {
+			c.SigningConfig.NonceStore = NewMemoryNonceStore()
+		}
+		if c.SigningConfig.MaxSignedBodySize <= 0 {
+			c.SigningConfig.MaxSignedBodySize = defaultMaxSignedBodySize
+		}
+		keys, err := normalizeSigningKeys(c.SigningConfig.Keys)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		c.SigningConfig.keys = keys
+	}
+
+	if c.ErrorRegistry == nil {
+		c.ErrorRegistry = DefaultErrorRegistry
+	}
+	if err := c.ErrorRegistry.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("ErrorRegistry: %w", err))
+	}
+
+	if !c.DisableMasking && c.MaskingConfig == nil {
+		c.MaskingConfig = &MaskingConfig{}
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		c.ShutdownTimeout = 30 * time.Second
+	}
+
 	if c.LogConfig == nil {
-		var logDir string
-		if runtime.GOOS == "windows" {
-			logDir = "C:\\BllLogs"
-		} else {
-			logDir = "/app/logs"
+		c.LogConfig = defaultLogConfig()
+	}
+
+	if !c.DisableHttpClientEnhance && c.HttpClientEnhanceConfig == nil {
+		c.HttpClientEnhanceConfig = &HttpClientEnhanceConfig{
+			Transport: originalDefaultTransport,
 		}
-		c.LogConfig = &LogConfig{
-			LogDir:           logDir,
-			MaxSize:          1024,
-			MaxBackups:       7,
-			RotationInterval: time.Hour * 24,
+	}
+	if !c.DisableHttpClientEnhance {
+		if err := c.HttpClientEnhanceConfig.initTLS(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.ServerTLSConfig != nil {
+		if err := c.ServerTLSConfig.init(); err != nil {
+			errs = append(errs, fmt.Errorf("ServerTLSConfig: %w", err))
 		}
 	}
 
-	if !c.DisableTransactionLog || !c.HttpClientEnhanceConfig.DisableTransactionLog {
-		svcCode := strings.ToLower(c.SvcCode)
-		filename := fmt.Sprintf(
-			"%s/%s_%s/%s_%s_info-info.log",
-			c.LogConfig.LogDir, svcCode, c.AppName, svcCode, c.AppName,
-		)
-		logger = &logrus.Logger{
-			Out: &lumberjack.Logger{
-				Filename:         filename,
+	if !c.DisableTransactionLog || c.outboundTransactionLogEnabled() {
+		if c.TransactionLogConfig == nil {
+			c.TransactionLogConfig = &TransactionLogConfig{}
+		}
+		if len(c.TransactionLogConfig.Sinks) == 0 {
+			c.TransactionLogConfig.Sinks = []TransactionLogSink{NewFileSink(c.LogConfig, c.logFilename("", "info-info"))}
+		}
+		for i, field := range c.TransactionLogConfig.Fields {
+			if field.Extract == nil {
+				errs = append(errs, fmt.Errorf("TransactionLogConfig.Fields: field %d (%s) has no Extract", i, field.Name))
+			}
+		}
+		for i, sink := range c.TransactionLogConfig.Sinks {
+			if sink == nil {
+				errs = append(errs, fmt.Errorf("TransactionLogConfig.Sinks: sink %d is nil", i))
+			}
+		}
+		if c.TransactionLogConfig.QueueSize <= 0 {
+			c.TransactionLogConfig.QueueSize = 4096
+		}
+		if c.TransactionLogConfig.Workers <= 0 {
+			c.TransactionLogConfig.Workers = 2
+		}
+		if c.TransactionLogConfig.RequestCaptureLimit <= 0 {
+			c.TransactionLogConfig.RequestCaptureLimit = defaultRequestCaptureLimit
+		}
+		if c.TransactionLogConfig.ResponseCaptureLimit <= 0 {
+			c.TransactionLogConfig.ResponseCaptureLimit = defaultResponseCaptureLimit
+		}
+		switch c.TransactionLogConfig.OverflowPolicy {
+		case OverflowDefault:
+			c.TransactionLogConfig.OverflowPolicy = OverflowBlock
+		case OverflowBlock, OverflowDropNewest, OverflowDropOldest:
+		default:
+			errs = append(errs, fmt.Errorf("TransactionLogConfig.OverflowPolicy: unknown policy %d", c.TransactionLogConfig.OverflowPolicy))
+		}
+	}
+
+	if !c.DisableTracing {
+		if c.TracingConfig == nil {
+			c.TracingConfig = &TracingConfig{}
+		}
+		if c.TracingConfig.QueueSize <= 0 {
+			c.TracingConfig.QueueSize = 2048
+		}
+		if c.TracingConfig.BatchSize <= 0 {
+			c.TracingConfig.BatchSize = 512
+		}
+		if c.TracingConfig.FlushInterval <= 0 {
+			c.TracingConfig.FlushInterval = 5 * time.Second
+		}
+		if c.TracingConfig.Exporter == nil {
+			c.TracingConfig.Exporter = NewJSONLinesExporter(&lumberjack.Logger{
+				Filename:         c.logFilename("", "span"),
 				MaxSize:          c.LogConfig.MaxSize,
 				MaxAge:           c.LogConfig.MaxAge,
 				MaxBackups:       c.LogConfig.MaxBackups,
 				LocalTime:        c.LogConfig.LocalTime,
 				Compress:         c.LogConfig.Compress,
 				RotationInterval: c.LogConfig.RotationInterval,
-			},
-			Formatter: new(PlainFormatter),
-			Level:     logrus.InfoLevel,
+			})
 		}
 	}
 
-	//if !c.DisableProgramLog {
-	//	c.initProgramLog()
-	//}
-
-	if !c.DisableHttpClientEnhance && c.HttpClientEnhanceConfig == nil {
-		c.HttpClientEnhanceConfig = &HttpClientEnhanceConfig{
-			Transport:         http.DefaultTransport,
-			DisableSkipVerify: false,
+	if !c.DisableProgramLog {
+		if c.ProgramLogConfig == nil {
+			c.ProgramLogConfig = &ProgramLogConfig{}
+		}
+		if c.ProgramLogConfig.Level == "" {
+			c.ProgramLogConfig.Level = "trace"
+		}
+		if _, err := logrus.ParseLevel(c.ProgramLogConfig.Level); err != nil {
+			errs = append(errs, fmt.Errorf("ProgramLogConfig.Level: %w", err))
+		}
+		if c.ProgramLogConfig.QueueSize <= 0 {
+			c.ProgramLogConfig.QueueSize = 1024
+		}
+		switch c.ProgramLogConfig.OverflowPolicy {
+		case OverflowDefault:
+			c.ProgramLogConfig.OverflowPolicy = OverflowDropOldest
+		case OverflowBlock, OverflowDropNewest, OverflowDropOldest:
+		default:
+			errs = append(errs, fmt.Errorf("ProgramLogConfig.OverflowPolicy: unknown policy %d", c.ProgramLogConfig.OverflowPolicy))
 		}
 	}
 
 	if len(errs) != 0 {
 		return errors.Join(errs...)
 	}
-	cnf = c
 	return nil
 }
 
-func (c *Config) initProgramLog() {
-	svcCode := strings.ToLower(c.SvcCode)
-
-	logLevels := []logrus.Level{
-		logrus.InfoLevel,
-		logrus.WarnLevel,
-		logrus.ErrorLevel,
-		logrus.FatalLevel,
-	}
-
-	writers := make(map[logrus.Level]io.Writer)
-
-	traceFilename := fmt.Sprintf(
-		"%s/%s_%s/trace/%s_%s_trace-trace.log",
-		c.LogConfig.LogDir, svcCode, c.AppName, svcCode, c.AppName,
-	)
-	writers[logrus.TraceLevel] = &lumberjack.Logger{
-		Filename:         traceFilename,
-		MaxSize:          c.LogConfig.MaxSize,
-		MaxAge:           c.LogConfig.MaxAge,
-		MaxBackups:       c.LogConfig.MaxBackups,
-		LocalTime:        c.LogConfig.LocalTime,
-		Compress:         c.LogConfig.Compress,
-		RotationInterval: c.LogConfig.RotationInterval,
+// validate 校验指标配置，Buckets 为 nil 时使用默认桶。
+func (m *MetricsConfig) validate() error {
+	var errs []error
+	if m.Buckets != nil && len(m.Buckets) == 0 {
+		errs = append(errs, errors.New("MetricsConfig.Buckets cannot be empty"))
 	}
-	debugFilename := fmt.Sprintf(
-		"%s/%s_%s/debug/%s_%s_code-debug.log",
-		c.LogConfig.LogDir, svcCode, c.AppName, svcCode, c.AppName,
-	)
-	writers[logrus.DebugLevel] = &lumberjack.Logger{
-		Filename:         debugFilename,
-		MaxSize:          c.LogConfig.MaxSize,
-		MaxAge:           c.LogConfig.MaxAge,
-		MaxBackups:       c.LogConfig.MaxBackups,
-		LocalTime:        c.LogConfig.LocalTime,
-		Compress:         c.LogConfig.Compress,
-		RotationInterval: c.LogConfig.RotationInterval,
+	for i, b := range m.Buckets {
+		if b <= 0 {
+			errs = append(errs, fmt.Errorf("MetricsConfig.Buckets[%d] must > 0, got %f", i, b))
+		}
 	}
-	for _, level := range logLevels {
-		filename := fmt.Sprintf(
-			"%s/%s_%s/%s_%s_code-%s.log",
-			c.LogConfig.LogDir, svcCode, c.AppName, svcCode, c.AppName, level,
-		)
-		writers[level] = &lumberjack.Logger{
-			Filename:         filename,
-			MaxSize:          c.LogConfig.MaxSize,
-			MaxAge:           c.LogConfig.MaxAge,
-			MaxBackups:       c.LogConfig.MaxBackups,
-			LocalTime:        c.LogConfig.LocalTime,
-			Compress:         c.LogConfig.Compress,
-			RotationInterval: c.LogConfig.RotationInterval,
+	for label := range m.CustomLabels {
+		if !metricLabelPattern.MatchString(label) {
+			errs = append(errs, fmt.Errorf("MetricsConfig.CustomLabels: invalid label name %q", label))
+		}
+		if reservedMetricLabels[label] {
+			errs = append(errs, fmt.Errorf("MetricsConfig.CustomLabels: label name %q is reserved", label))
 		}
 	}
+	return errors.Join(errs...)
+}
 
-	logrus.AddHook(&LevelHook{writers, new(PlainFormatter)})
-	logrus.SetLevel(logrus.TraceLevel)
-	logrus.SetOutput(io.Discard)
+// normalizeSigningKeys 服务编码不区分大小写，与 Context.GetFCode 一致按大写查找。
+func normalizeSigningKeys(keys map[string]string) (map[string]string, error) {
+	var errs []error
+	normalized := make(map[string]string, len(keys))
+	for fcode, key := range keys {
+		if key == "" {
+			errs = append(errs, fmt.Errorf("SigningConfig.Keys: key for %q is empty", fcode))
+		}
+		upper := strings.ToUpper(fcode)
+		if _, ok := normalized[upper]; ok {
+			errs = append(errs, fmt.Errorf("SigningConfig.Keys: duplicate key for %q", upper))
+		}
+		normalized[upper] = key
+	}
+	return normalized, errors.Join(errs...)
 }
@@ -0,0 +1,536 @@
+package ginqq
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const defaultEnvPrefix = "GINQQ"
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// LoadConfig 从 YAML（.yaml、.yml）或 TOML（.toml）文件加载配置，再使用 GINQQ_ 前缀的环境变量覆盖，
+// 返回的配置可在代码中继续修改后传给 NewEngineWithConfig，优先级：配置文件 < 环境变量 < 代码。
+//
+// 配置项为字段名的蛇形命名，如 svc_code、log_config.log_dir、metrics_config.buckets，
+// 时长使用 "5s"、"24h" 格式，OverflowPolicy、MaskStrategy 使用名称（如 "drop-oldest"、"partial"）。
+// 文件顶层 profiles 下可按环境（如 dev、test、prod）配置，选中的环境覆盖顶层配置，
+// 环境由环境变量 GINQQ_PROFILE 指定，未设置时使用文件顶层的 profile 。
+// Sinks、Exporter、Transport 等接口类型只能在代码中设置。
+//
+// 所有配置项的错误汇总后返回，解析成功后校验已配置项的取值（如正则、枚举、证书文件），校验错误同样汇总返回。
+// 必填项（如 SvcCode、AppName）可在代码中设置，由 NewEngineWithConfig 校验。
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var data map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &data)
+	case ".toml":
+		err = toml.Unmarshal(content, &data)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q, expected .yaml, .yml or .toml", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	d := newConfigDecoder(defaultEnvPrefix)
+	c := &Config{}
+	if data, err = d.applyProfile(data); err != nil {
+		return nil, err
+	}
+	d.decodeStruct(reflect.ValueOf(c).Elem(), data, "")
+	d.decodeEnv(reflect.ValueOf(c).Elem(), d.prefix)
+	if len(d.errs) != 0 {
+		return nil, errors.Join(d.errs...)
+	}
+	if err := validateConfig(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ConfigFromEnv 从环境变量加载配置，prefix 为空时使用 "GINQQ"。
+// 变量名为前缀加大写的配置项路径，如 GINQQ_SVC_CODE、GINQQ_LOG_CONFIG_LOG_DIR、GINQQ_METRICS_CONFIG_BUCKETS，
+// 列表以逗号分隔（如 "100,500,1000"），映射以逗号分隔的 key=value 表示（如 "operate_type=OPERATE_TYPE"）。
+// 与 LoadConfig 相同，返回前校验已配置项的取值，必填项由 NewEngineWithConfig 校验。
+func ConfigFromEnv(prefix string) (*Config, error) {
+	if prefix == "" {
+		prefix = defaultEnvPrefix
+	}
+	d := newConfigDecoder(strings.TrimSuffix(prefix, "_"))
+	c := &Config{}
+	d.decodeEnv(reflect.ValueOf(c).Elem(), d.prefix)
+	if len(d.errs) != 0 {
+		return nil, errors.Join(d.errs...)
+	}
+	if err := validateConfig(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// validateConfig 校验配置文件、环境变量中已配置项的取值，与创建实例时的规则一致，
+// 不校验必填项、不填充默认值、不加载证书及创建输出，返回的配置可在代码中继续修改。
+func validateConfig(c *Config) error {
+	var errs []error
+	if _, err := parseServiceIdentity(c.SvcCode, c.AppName, false); err != nil {
+		errs = append(errs, err)
+	}
+	if c.MetricsConfig != nil {
+		if err := c.MetricsConfig.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg := c.ApiStandardServerConfig; cfg != nil && cfg.MethodCodePattern != "" {
+		if _, err := regexp.Compile(cfg.MethodCodePattern); err != nil {
+			errs = append(errs, fmt.Errorf("ApiStandardServerConfig.MethodCodePattern: %w", err))
+		}
+	}
+	if c.SecurityConfig != nil {
+		if _, err := compileSecurityRules(c.SecurityConfig); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.SigningConfig != nil {
+		if _, err := normalizeSigningKeys(c.SigningConfig.Keys); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.MaskingConfig != nil {
+		if _, err := newMasker(c.MaskingConfig); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if cfg := c.HttpClientEnhanceConfig; cfg != nil {
+		if cfg.TLS != nil {
+			if err := validateTLSFiles(cfg.TLS.CAFiles, cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.Pins); err != nil {
+				errs = append(errs, fmt.Errorf("HttpClientEnhanceConfig.TLS: %w", err))
+			}
+		}
+		for host, t := range cfg.HostTLS {
+			if t == nil {
+				continue
+			}
+			if err := validateTLSFiles(t.CAFiles, t.CertFile, t.KeyFile, t.Pins); err != nil {
+				errs = append(errs, fmt.Errorf("HttpClientEnhanceConfig.HostTLS[%q]: %w", host, err))
+			}
+		}
+	}
+	if t := c.ServerTLSConfig; t != nil {
+		if err := validateTLSFiles(t.ClientCAFiles, t.CertFile, t.KeyFile, nil); err != nil {
+			errs = append(errs, fmt.Errorf("ServerTLSConfig: %w", err))
+		}
+	}
+	if c.TransactionLogConfig != nil && !validOverflowPolicy(c.TransactionLogConfig.OverflowPolicy) {
+		errs = append(errs, fmt.Errorf("TransactionLogConfig.OverflowPolicy: unknown policy %d", c.TransactionLogConfig.OverflowPolicy))
+	}
+	if cfg := c.ProgramLogConfig; cfg != nil {
+		if cfg.Level != "" {
+			if _, err := logrus.ParseLevel(cfg.Level); err != nil {
+				errs = append(errs, fmt.Errorf("ProgramLogConfig.Level: %w", err))
+			}
+		}
+		if !validOverflowPolicy(cfg.OverflowPolicy) {
+			errs = append(errs, fmt.Errorf("ProgramLogConfig.OverflowPolicy: unknown policy %d", cfg.OverflowPolicy))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateTLSFiles 校验证书文件存在、证书与私钥成对配置及公钥固定格式，不加载证书。
+func validateTLSFiles(caFiles []string, certFile, keyFile string, pins []string) error {
+	var errs []error
+	if (certFile == "") != (keyFile == "") {
+		errs = append(errs, errors.New("CertFile and KeyFile must be set together"))
+	}
+	for _, file := range append(append([]string(nil), caFiles...), certFile, keyFile) {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if _, err := parsePins(pins); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func validOverflowPolicy(p OverflowPolicy) bool {
+	switch p {
+	case OverflowDefault, OverflowBlock, OverflowDropNewest, OverflowDropOldest:
+		return true
+	}
+	return false
+}
+
+// configDecoder 按字段名将配置文件数据、环境变量写入 Config，错误汇总记录。
+type configDecoder struct {
+	prefix  string
+	environ map[string]string // 以 prefix 开头的环境变量
+	errs    []error
+}
+
+func newConfigDecoder(prefix string) *configDecoder {
+	d := &configDecoder{prefix: prefix, environ: make(map[string]string)}
+	for _, kv := range os.Environ() {
+		if key, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(key, prefix+"_") {
+			d.environ[key] = value
+		}
+	}
+	return d
+}
+
+func (d *configDecoder) fail(path string, format string, args ...interface{}) {
+	d.errs = append(d.errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+// applyProfile 将选中环境的配置合并到顶层配置。
+func (d *configDecoder) applyProfile(data map[string]interface{}) (map[string]interface{}, error) {
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	profile := d.environ[d.prefix+"_PROFILE"]
+	if profile == "" {
+		profile, _ = data["profile"].(string)
+	}
+	profiles, hasProfiles := data["profiles"]
+	delete(data, "profile")
+	delete(data, "profiles")
+	if profile == "" || !hasProfiles {
+		return data, nil
+	}
+
+	sections, ok := profiles.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("profiles: expected a mapping of profile name to configuration")
+	}
+	section, ok := sections[profile]
+	if !ok {
+		names := make([]string, 0, len(sections))
+		for name := range sections {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("profiles: profile %q not found, available: %s", profile, strings.Join(names, ", "))
+	}
+	overrides, ok := section.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("profiles.%s: expected a mapping", profile)
+	}
+	mergeConfigData(data, overrides)
+	return data, nil
+}
+
+// mergeConfigData 将 src 深度合并到 dst，映射逐项合并，其他值整体覆盖。
+func mergeConfigData(dst, src map[string]interface{}) {
+	for key, value := range src {
+		if srcMap, ok := value.(map[string]interface{}); ok {
+			if dstMap, ok := dst[key].(map[string]interface{}); ok {
+				mergeConfigData(dstMap, srcMap)
+				continue
+			}
+		}
+		dst[key] = value
+	}
+}
+
+// decodeStruct 写入配置文件中的结构体数据，键名忽略大小写和下划线，如 svc_code 可匹配 SvcCode 。
+func (d *configDecoder) decodeStruct(v reflect.Value, data map[string]interface{}, path string) {
+	fields := make(map[string]int)
+	for i := 0; i < v.NumField(); i++ {
+		if field := v.Type().Field(i); field.IsExported() {
+			fields[simplifyKey(field.Name)] = i
+		}
+	}
+	for key, raw := range data {
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+		i, ok := fields[simplifyKey(key)]
+		if !ok {
+			d.fail(fieldPath, "unknown configuration key")
+			continue
+		}
+		d.decodeValue(v.Field(i), raw, fieldPath)
+	}
+}
+
+func (d *configDecoder) decodeValue(v reflect.Value, raw interface{}, path string) {
+	if raw == nil {
+		return
+	}
+	if s, ok := raw.(string); ok && (v.Type() == durationType || reflect.PointerTo(v.Type()).Implements(textUnmarshalerType)) {
+		d.setString(v, s, path)
+		return
+	}
+	if v.Type() == durationType {
+		d.fail(path, "expected a duration such as \"5s\", got %v", raw)
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			d.fail(path, "unsupported field type %s", v.Type())
+			return
+		}
+		if v.IsNil() {
+			v.Set(newConfigStruct(v.Type().Elem()))
+		}
+		d.decodeValue(v.Elem(), raw, path)
+	case reflect.Struct:
+		data, ok := raw.(map[string]interface{})
+		if !ok {
+			d.fail(path, "expected a mapping, got %T", raw)
+			return
+		}
+		d.decodeStruct(v, data, path)
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok {
+			d.fail(path, "expected a string, got %T", raw)
+			return
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		b, ok := raw.(bool)
+		if !ok {
+			d.fail(path, "expected a boolean, got %T", raw)
+			return
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := configInt(raw)
+		if !ok || v.OverflowInt(n) {
+			d.fail(path, "expected an integer, got %v", raw)
+			return
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, ok := configFloat(raw)
+		if !ok {
+			d.fail(path, "expected a number, got %v", raw)
+			return
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		items, ok := raw.([]interface{})
+		if !ok {
+			d.fail(path, "expected a list, got %T", raw)
+			return
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			d.decodeValue(slice.Index(i), item, fmt.Sprintf("%s[%d]", path, i))
+		}
+		v.Set(slice)
+	case reflect.Map:
+		data, ok := raw.(map[string]interface{})
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			d.fail(path, "expected a mapping, got %T", raw)
+			return
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(data))
+		for key, item := range data {
+			value := reflect.New(v.Type().Elem()).Elem()
+			d.decodeValue(value, item, path+"."+key)
+			m.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), value)
+		}
+		v.Set(m)
+	default:
+		d.fail(path, "%s can only be set in code", v.Type())
+	}
+}
+
+// decodeEnv 写入环境变量，变量名为 prefix 加大写的字段路径，结构体指针只在存在对应变量时创建。
+func (d *configDecoder) decodeEnv(v reflect.Value, prefix string) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(snakeCase(field.Name))
+		fv := v.Field(i)
+
+		switch {
+		case field.Type.Kind() == reflect.Pointer && field.Type.Elem().Kind() == reflect.Struct:
+			if !d.hasEnvPrefix(name + "_") {
+				continue
+			}
+			if fv.IsNil() {
+				fv.Set(newConfigStruct(field.Type.Elem()))
+			}
+			d.decodeEnv(fv.Elem(), name)
+		case field.Type.Kind() == reflect.Struct && field.Type != durationType:
+			d.decodeEnv(fv, name)
+		default:
+			if value, ok := d.environ[name]; ok {
+				d.setString(fv, value, name)
+			}
+		}
+	}
+}
+
+func (d *configDecoder) hasEnvPrefix(prefix string) bool {
+	for key := range d.environ {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// setString 解析字符串形式的配置值。
+func (d *configDecoder) setString(v reflect.Value, s string, path string) {
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			d.fail(path, "%v", err)
+		}
+		return
+	}
+	if v.Type() == durationType {
+		duration, err := time.ParseDuration(s)
+		if err != nil {
+			d.fail(path, "invalid duration %q", s)
+			return
+		}
+		v.SetInt(int64(duration))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			d.fail(path, "invalid boolean %q", s)
+			return
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			d.fail(path, "invalid integer %q", s)
+			return
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			d.fail(path, "invalid number %q", s)
+			return
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		if s = strings.TrimSpace(s); s != "" {
+			items = strings.Split(s, ",")
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			d.setString(slice.Index(i), strings.TrimSpace(item), fmt.Sprintf("%s[%d]", path, i))
+		}
+		v.Set(slice)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			d.fail(path, "unsupported field type %s", v.Type())
+			return
+		}
+		m := reflect.MakeMap(v.Type())
+		for _, pair := range strings.Split(s, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			key, item, ok := strings.Cut(pair, "=")
+			if !ok {
+				d.fail(path, "invalid key=value pair %q", pair)
+				continue
+			}
+			value := reflect.New(v.Type().Elem()).Elem()
+			d.setString(value, strings.TrimSpace(item), path+"."+strings.TrimSpace(key))
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(key)).Convert(v.Type().Key()), value)
+		}
+		v.Set(m)
+	default:
+		d.fail(path, "%s can only be set in code", v.Type())
+	}
+}
+
+// newConfigStruct 创建配置结构体，LogConfig 以默认值为基础，未配置的项保持默认。
+func newConfigStruct(t reflect.Type) reflect.Value {
+	if t == reflect.TypeOf(LogConfig{}) {
+		return reflect.ValueOf(defaultLogConfig())
+	}
+	return reflect.New(t)
+}
+
+func configInt(raw interface{}) (int64, bool) {
+	switch n := raw.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), n <= 1<<63-1
+	case float64:
+		return int64(n), n == float64(int64(n))
+	}
+	return 0, false
+}
+
+func configFloat(raw interface{}) (float64, bool) {
+	switch n := raw.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// snakeCase 将字段名转为蛇形命名，如 HttpClientEnhanceConfig 转为 http_client_enhance_config 。
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,190 @@
+package ginqq
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigYAML(t *testing.T) {
+	path := writeConfigFile(t, "ginqq.yaml", `
+svc_code: A186010101
+app_name: order
+shutdown_timeout: 10s
+metrics_config:
+  buckets: [50, 100]
+  custom_labels:
+    operate_type: OPERATE_TYPE
+transaction_log_config:
+  overflow_policy: drop-oldest
+  text_content_types: [application/x-ndjson]
+masking_config:
+  rules:
+    - keys: [customer_name]
+      strategy: partial
+      keep_prefix: 1
+log_config:
+  log_dir: /var/log/app
+profiles:
+  prod:
+    log_config:
+      max_backups: 30
+    http_client_enhance_config:
+      disable_skip_verify: true
+`)
+	t.Setenv("GINQQ_PROFILE", "prod")
+	t.Setenv("GINQQ_APP_NAME", "order_api")
+	t.Setenv("GINQQ_METRICS_CONFIG_PATH", "/internal/metrics")
+
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.ShutdownTimeout = 5 * time.Second // 代码中的设置优先
+
+	if c.SvcCode != "A186010101" || c.AppName != "order_api" || c.ShutdownTimeout != 5*time.Second {
+		t.Errorf("unexpected top-level fields: %+v", c)
+	}
+	if !reflect.DeepEqual(c.MetricsConfig, &MetricsConfig{
+		Buckets:      []float64{50, 100},
+		CustomLabels: map[string]string{"operate_type": "OPERATE_TYPE"},
+		Path:         "/internal/metrics",
+	}) {
+		t.Errorf("unexpected MetricsConfig: %+v", c.MetricsConfig)
+	}
+	if c.TransactionLogConfig.OverflowPolicy != OverflowDropOldest || c.TransactionLogConfig.TextContentTypes[0] != "application/x-ndjson" {
+		t.Errorf("unexpected TransactionLogConfig: %+v", c.TransactionLogConfig)
+	}
+	if rule := c.MaskingConfig.Rules[0]; rule.Strategy != MaskPartial || rule.KeepPrefix != 1 || rule.Keys[0] != "customer_name" {
+		t.Errorf("unexpected mask rule: %+v", rule)
+	}
+	// 未配置的日志项保持默认值
+	if c.LogConfig.LogDir != "/var/log/app" || c.LogConfig.MaxBackups != 30 || c.LogConfig.MaxSize != 1024 {
+		t.Errorf("unexpected LogConfig: %+v", c.LogConfig)
+	}
+	if !c.HttpClientEnhanceConfig.DisableSkipVerify {
+		t.Error("profile override not applied to HttpClientEnhanceConfig")
+	}
+}
+
+func TestLoadConfigTOML(t *testing.T) {
+	path := writeConfigFile(t, "ginqq.toml", `
+svc_code = "A186010101"
+app_name = "order"
+profile = "dev"
+
+[tracing_config]
+flush_interval = "1s"
+
+[profiles.dev]
+disable_metrics = true
+`)
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.TracingConfig.FlushInterval != time.Second || !c.DisableMetrics {
+		t.Errorf("unexpected config: %+v", c)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	path := writeConfigFile(t, "ginqq.yaml", `
+svc_code: 1
+shutdown_timeout: 10
+metric_config: {}
+transaction_log_config:
+  overflow_policy: drop-all
+`)
+	t.Setenv("GINQQ_LOG_CONFIG_MAX_SIZE", "big")
+
+	_, err := LoadConfig(path)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{
+		"svc_code: expected a string",
+		"shutdown_timeout: expected a duration",
+		"metric_config: unknown configuration key",
+		"transaction_log_config.overflow_policy: unknown overflow policy",
+		`GINQQ_LOG_CONFIG_MAX_SIZE: invalid integer "big"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error missing %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	// 必填项可在代码中设置，加载时不校验
+	path := writeConfigFile(t, "ginqq.yaml", `
+log_config:
+  log_dir: /var/log/app
+`)
+	c, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.SvcCode != "" || c.MetricsConfig != nil || c.TransactionLogConfig != nil {
+		t.Errorf("loaded config should not be filled with defaults: %+v", c)
+	}
+
+	path = writeConfigFile(t, "ginqq.yaml", `
+app_name: Order-API!
+api_standard_server_config:
+  method_code_pattern: "["
+server_tls_config:
+  cert_file: /nonexistent/server.crt
+`)
+	_, err = LoadConfig(path)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{`"AppName" "Order-API!" is invalid`, "ApiStandardServerConfig.MethodCodePattern",
+		"ServerTLSConfig: CertFile and KeyFile must be set together", "/nonexistent/server.crt"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error missing %q:\n%v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), `"SvcCode" is required`) {
+		t.Errorf("required field checked on load:\n%v", err)
+	}
+
+	t.Setenv("GINQQ_SVC_CODE", "A18601")
+	if _, err := ConfigFromEnv(""); err == nil || !strings.Contains(err.Error(), `"SvcCode" "A18601" is invalid`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("APP_SVC_CODE", "A186010101")
+	t.Setenv("APP_DISABLE_TRACING", "true")
+	t.Setenv("APP_METRICS_CONFIG_BUCKETS", "10, 20")
+	t.Setenv("APP_METRICS_CONFIG_CUSTOM_LABELS", "operate_type=OPERATE_TYPE")
+	t.Setenv("APP_TRANSACTION_LOG_CONFIG_QUEUE_SIZE", "128")
+
+	c, err := ConfigFromEnv("APP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.SvcCode != "A186010101" || !c.DisableTracing || c.TransactionLogConfig.QueueSize != 128 {
+		t.Errorf("unexpected config: %+v", c)
+	}
+	if !reflect.DeepEqual(c.MetricsConfig.Buckets, []float64{10, 20}) || c.MetricsConfig.CustomLabels["operate_type"] != "OPERATE_TYPE" {
+		t.Errorf("unexpected MetricsConfig: %+v", c.MetricsConfig)
+	}
+	if c.LogConfig != nil || c.HttpClientEnhanceConfig != nil {
+		t.Error("configs without environment variables should stay nil")
+	}
+}
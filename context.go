@@ -4,6 +4,7 @@ package ginqq
 import (
 	"bytes"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 	"io"
 	"strconv"
 	"strings"
@@ -16,6 +17,8 @@ const (
 	XMethodCode      = "Method-Code"
 	XMethodName      = "Method-Name"
 	XResponsePayload = "Response-Payload"
+
+	xEngine = "ginqq.engine" // ctx 中保存请求所属 GinQQ 实例的 key
 )
 
 type Context struct {
@@ -26,6 +29,42 @@ func Wrap(c *gin.Context) *Context {
 	return &Context{Context: c}
 }
 
+// Engine 返回处理当前请求的 GinQQ 实例，未通过 Default 或 NewEngineWithConfig 创建的引擎返回 nil 。
+func (c *Context) Engine() *GinQQ {
+	if engine, ok := c.Get(xEngine); ok {
+		return engine.(*GinQQ)
+	}
+	return nil
+}
+
+// Config 返回处理当前请求的 GinQQ 实例的配置，未关联实例时返回 nil 。
+func (c *Context) Config() *Config {
+	if engine := c.Engine(); engine != nil {
+		return engine.Config
+	}
+	return nil
+}
+
+// Logger 返回当前请求的程序日志 logger，日志附带 trace_id、transaction_id、method_code、method_name、fcode，
+// 可与流水日志关联，开启链路时附带 span_id 。
+func (c *Context) Logger() *logrus.Entry {
+	base := logrus.StandardLogger()
+	if engine := c.Engine(); engine != nil {
+		base = engine.Logger()
+	}
+	fields := logrus.Fields{
+		"trace_id":       c.GetTraceID(),
+		"transaction_id": c.GetTransactionID(),
+		"method_code":    c.GetMethodCode(),
+		"method_name":    c.GetMethodName(),
+		"fcode":          c.GetFCode(),
+	}
+	if spanID := c.GetSpanID(); spanID != "" {
+		fields["span_id"] = spanID
+	}
+	return base.WithFields(fields)
+}
+
 func (c *Context) IndentedJSON(code int, obj interface{}) {
 	c.Set(XResponsePayload, obj)
 	c.Context.IndentedJSON(code, obj)
@@ -68,10 +107,11 @@ func (c *Context) GetRawDataReusable() ([]byte, error) {
 	return body, err
 }
 
+// GetTraceID 获取链路ID，优先取 W3C traceparent 中的 trace-id，其次取 Trace-ID 请求头，都没有时生成。
 func (c *Context) GetTraceID() string {
 	traceID := c.GetString(XTraceID)
 	if traceID == "" {
-		traceID = c.GetHeader(XTraceID)
+		traceID = c.headerTraceID()
 		if traceID == "" {
 			traceID = uuid4()
 		}
@@ -80,6 +120,30 @@ func (c *Context) GetTraceID() string {
 	return traceID
 }
 
+// headerTraceID 从请求头获取链路ID，优先取 W3C traceparent 中的 trace-id，其次取 Trace-ID 请求头。
+func (c *Context) headerTraceID() string {
+	if id, _, _, ok := parseTraceparent(c.GetHeader(XTraceparent)); ok {
+		return id
+	}
+	return c.GetHeader(XTraceID)
+}
+
+// GetSpanID 获取当前服务端 Span 的ID，未开启链路时返回空字符串。
+func (c *Context) GetSpanID() string {
+	if span := c.GetSpan(); span != nil {
+		return span.SpanID
+	}
+	return ""
+}
+
+// GetSpan 获取当前服务端 Span，可用于设置自定义属性，未开启链路时返回 nil 。
+func (c *Context) GetSpan() *Span {
+	if span, ok := c.Get(xSpan); ok {
+		return span.(*Span)
+	}
+	return nil
+}
+
 func (c *Context) GetTransactionID() string {
 	transactionID := c.GetString(XTransactionID)
 	if transactionID == "" {
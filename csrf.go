@@ -0,0 +1,247 @@
+package ginqq
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// xCSRFToken ctx 中保存当前请求 CSRF 令牌的 key 。
+const xCSRFToken = "ginqq.csrf_token"
+
+var ErrCSRFRejected = RegisterError(ErrorDefinition{
+	Code: CodeCSRFRejected, HTTPStatus: http.StatusForbidden, Category: ErrorCategoryClient,
+	MessageZh: "请求未通过CSRF校验", MessageEn: "CSRF validation failed",
+})
+
+// CSRFConfig CSRF 防护配置，未设置 Session 时使用双重提交 cookie 模式，设置时使用与会话绑定的同步令牌模式。
+type CSRFConfig struct {
+	// Secret 令牌签名密钥，为空时随机生成，多副本部署需配置相同的密钥。
+	Secret []byte
+
+	// Session 返回当前请求的会话标识，返回非空时令牌与会话绑定，无需 cookie（同步令牌模式）。
+	Session func(*Context) string
+
+	CookieName string        // 令牌 cookie 名称，默认 "csrf_token"
+	CookiePath string        // 令牌 cookie 路径，默认 "/"
+	SameSite   http.SameSite // 令牌 cookie 的 SameSite，默认 Lax
+	HeaderName string        // 提交令牌的请求头，默认 "X-CSRF-Token"
+	FormField  string        // 提交令牌的表单字段，默认 "_csrf"
+	TokenTTL   time.Duration // 令牌有效期，默认 12 小时
+
+	SafeMethods []string // 不校验的请求方法，默认 GET、HEAD、OPTIONS、TRACE
+
+	// TrustedOrigins 允许跨域提交的来源，如 "https://portal.example.com"，默认只允许与请求同源。
+	TrustedOrigins []string
+
+	// DisableServiceExemption 不豁免服务间调用。默认 FCode（User-Agent）为合法服务编码的请求视为服务间调用，不做 CSRF 校验。
+	DisableServiceExemption bool
+}
+
+type csrfProtector struct {
+	cfg            CSRFConfig
+	safeMethods    map[string]bool
+	trustedOrigins map[string]bool
+}
+
+// CSRF CSRF 防护中间件，用于面向浏览器的路由组：
+//
+//	web := r.Group("/web")
+//	web.Use(ginqq.CSRF(nil))
+//
+// 安全方法的请求签发令牌（cookie 及 Context.CSRFToken），其他请求校验 Origin/Referer 及提交的令牌，
+// 校验失败时以标准响应格式拒绝，原因记录在流水 response_remark 中。cfg 为 nil 时使用默认配置。
+func CSRF(cfg *CSRFConfig) func(*Context) {
+	p := newCSRFProtector(cfg)
+	return p.handle
+}
+
+func newCSRFProtector(cfg *CSRFConfig) *csrfProtector {
+	p := &csrfProtector{}
+	if cfg != nil {
+		p.cfg = *cfg
+	}
+	if len(p.cfg.Secret) == 0 {
+		p.cfg.Secret = make([]byte, 32)
+		_, _ = rand.Read(p.cfg.Secret)
+	}
+	if p.cfg.CookieName == "" {
+		p.cfg.CookieName = "csrf_token"
+	}
+	if p.cfg.CookiePath == "" {
+		p.cfg.CookiePath = "/"
+	}
+	if p.cfg.SameSite == 0 {
+		p.cfg.SameSite = http.SameSiteLaxMode
+	}
+	if p.cfg.HeaderName == "" {
+		p.cfg.HeaderName = "X-CSRF-Token"
+	}
+	if p.cfg.FormField == "" {
+		p.cfg.FormField = "_csrf"
+	}
+	if p.cfg.TokenTTL <= 0 {
+		p.cfg.TokenTTL = 12 * time.Hour
+	}
+	if p.cfg.SafeMethods == nil {
+		p.cfg.SafeMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace}
+	}
+	p.safeMethods = make(map[string]bool, len(p.cfg.SafeMethods))
+	for _, method := range p.cfg.SafeMethods {
+		p.safeMethods[strings.ToUpper(method)] = true
+	}
+	p.trustedOrigins = make(map[string]bool, len(p.cfg.TrustedOrigins))
+	for _, origin := range p.cfg.TrustedOrigins {
+		p.trustedOrigins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+	return p
+}
+
+func (p *csrfProtector) handle(c *Context) {
+	session := p.session(c)
+	cookieToken := ""
+	if session == "" {
+		cookieToken, _ = c.Cookie(p.cfg.CookieName)
+		if !p.valid(cookieToken, "") {
+			cookieToken = ""
+		}
+	}
+
+	if p.safeMethods[c.Request.Method] {
+		c.Set(xCSRFToken, p.issue(c, session, cookieToken))
+		c.Next()
+		return
+	}
+	if !p.cfg.DisableServiceExemption && svcCodePattern.MatchString(c.GetFCode()) {
+		c.Next()
+		return
+	}
+	if reason := p.verify(c, session, cookieToken); reason != "" {
+		c.Logger().WithField("csrf_reason", reason).Warn("request rejected by CSRF protection")
+		c.Fail(ErrCSRFRejected.WithRemark("csrf: " + reason))
+		return
+	}
+	c.Set(xCSRFToken, p.issue(c, session, cookieToken))
+	c.Next()
+}
+
+// verify 校验来源及令牌，返回拒绝原因，通过时返回空。
+func (p *csrfProtector) verify(c *Context, session, cookieToken string) string {
+	origin := c.GetHeader("Origin")
+	source := "Origin"
+	if origin == "" || origin == "null" {
+		if referer := c.GetHeader("Referer"); referer != "" {
+			if u, err := url.Parse(referer); err == nil {
+				origin, source = u.Scheme+"://"+u.Host, "Referer"
+			}
+		}
+	}
+	if origin != "" && !p.trustedOrigin(c, origin) {
+		return source + " " + origin + " is not allowed"
+	}
+
+	token := c.GetHeader(p.cfg.HeaderName)
+	if token == "" {
+		mediaType, _, _ := mime.ParseMediaType(c.ContentType())
+		if mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data" {
+			token = c.PostForm(p.cfg.FormField)
+		}
+	}
+	switch {
+	case token == "":
+		return "token missing"
+	case session == "" && cookieToken == "":
+		return "token cookie missing or expired"
+	case session == "" && !hmac.Equal([]byte(token), []byte(cookieToken)):
+		return "token does not match cookie"
+	case !p.valid(token, session):
+		return "token invalid or expired"
+	}
+	return ""
+}
+
+func (p *csrfProtector) trustedOrigin(c *Context, origin string) bool {
+	origin = strings.ToLower(origin)
+	if p.trustedOrigins[origin] {
+		return true
+	}
+	scheme := "http"
+	if c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	return origin == scheme+"://"+strings.ToLower(c.Request.Host)
+}
+
+func (p *csrfProtector) session(c *Context) string {
+	if p.cfg.Session == nil {
+		return ""
+	}
+	return p.cfg.Session(c)
+}
+
+// issue 返回当前请求可用的令牌，双重提交模式下没有有效 cookie 时签发新令牌并写入 cookie 。
+func (p *csrfProtector) issue(c *Context, session, cookieToken string) string {
+	if session != "" {
+		return p.newToken(session)
+	}
+	if cookieToken != "" {
+		return cookieToken
+	}
+	token := p.newToken("")
+	secure := c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")
+	// 前端需读取 cookie 放入请求头，不设置 HttpOnly
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     p.cfg.CookieName,
+		Value:    token,
+		Path:     p.cfg.CookiePath,
+		MaxAge:   int(p.cfg.TokenTTL / time.Second),
+		Secure:   secure,
+		SameSite: p.cfg.SameSite,
+	})
+	return token
+}
+
+// newToken 生成令牌：base64url(16 字节随机数 + 8 字节签发时间) + "." + base64url(HMAC-SHA256(随机数 + 签发时间 + 会话标识)) 。
+func (p *csrfProtector) newToken(session string) string {
+	payload := make([]byte, 24)
+	_, _ = rand.Read(payload[:16])
+	binary.BigEndian.PutUint64(payload[16:], uint64(time.Now().Unix()))
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(p.sign(payload, session))
+}
+
+func (p *csrfProtector) valid(token, session string) bool {
+	encodedPayload, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil || len(payload) != 24 {
+		return false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !hmac.Equal(sig, p.sign(payload, session)) {
+		return false
+	}
+	issuedAt := time.Unix(int64(binary.BigEndian.Uint64(payload[16:])), 0)
+	return time.Since(issuedAt) <= p.cfg.TokenTTL
+}
+
+func (p *csrfProtector) sign(payload []byte, session string) []byte {
+	mac := hmac.New(sha256.New, p.cfg.Secret)
+	mac.Write(payload)
+	mac.Write([]byte(session))
+	return mac.Sum(nil)
+}
+
+// CSRFToken 返回当前请求的 CSRF 令牌，用于嵌入页面表单（字段名默认 _csrf）或由前端放入 X-CSRF-Token 请求头，
+// 未经过 CSRF 中间件时返回空。
+func (c *Context) CSRFToken() string {
+	return c.GetString(xCSRFToken)
+}
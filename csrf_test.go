@@ -0,0 +1,106 @@
+package ginqq
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	lines := make(chan []byte, 1)
+	g := newTestGinQQ(t, &Config{}, func(msg []byte) { lines <- msg })
+	g.Use(DispatchTransactionLog)
+	web := g.Group("/web")
+	web.Use(CSRF(&CSRFConfig{Secret: []byte("secret"), TrustedOrigins: []string{"https://portal.example.com"}}))
+	web.GET("/form", func(c *Context) { c.String(http.StatusOK, c.CSRFToken()) })
+	web.POST("/orders", func(c *Context) { c.Success(nil) })
+
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/web/form", nil))
+	<-lines
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "csrf_token" || cookies[0].Value != w.Body.String() {
+		t.Fatalf("token not issued: cookies %v, body %q", cookies, w.Body)
+	}
+	token := w.Body.String()
+
+	for _, tt := range []struct {
+		name    string
+		cookie  string
+		header  map[string]string
+		form    url.Values
+		wantErr string
+	}{
+		{name: "header", cookie: token, header: map[string]string{"X-CSRF-Token": token}},
+		{name: "form", cookie: token, form: url.Values{"_csrf": {token}}},
+		{name: "trusted origin", cookie: token, header: map[string]string{"X-CSRF-Token": token, "Origin": "https://portal.example.com"}},
+		{name: "same origin referer", cookie: token, header: map[string]string{"X-CSRF-Token": token, "Referer": "http://example.com/web/form"}},
+		{name: "service call", header: map[string]string{XFCode: "B186010101"}},
+		{name: "missing token", cookie: token, wantErr: "csrf: token missing"},
+		{name: "missing cookie", header: map[string]string{"X-CSRF-Token": token}, wantErr: "csrf: token cookie missing or expired"},
+		{name: "forged token", cookie: "a.b", header: map[string]string{"X-CSRF-Token": "a.b"}, wantErr: "csrf: token cookie missing or expired"},
+		{name: "mismatch", cookie: token, header: map[string]string{"X-CSRF-Token": token + "x"}, wantErr: "csrf: token does not match cookie"},
+		{name: "cross origin", cookie: token, header: map[string]string{"X-CSRF-Token": token, "Origin": "https://evil.example.com"},
+			wantErr: "csrf: Origin https://evil.example.com is not allowed"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/web/orders", nil)
+			if tt.form != nil {
+				req = httptest.NewRequest(http.MethodPost, "/web/orders", strings.NewReader(tt.form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "csrf_token", Value: tt.cookie})
+			}
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			g.ServeHTTP(w, req)
+			var log TransactionLog
+			if err := json.Unmarshal(<-lines, &log); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr == "" {
+				if w.Code != http.StatusOK {
+					t.Errorf("status %d: %s (%s)", w.Code, w.Body, log.ResponseRemark)
+				}
+				return
+			}
+			if w.Code != http.StatusForbidden || log.ErrorCode != CodeCSRFRejected || log.ResponseRemark != tt.wantErr {
+				t.Errorf("got %d, error_code %q, response_remark %q, want %q", w.Code, log.ErrorCode, log.ResponseRemark, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCSRFSessionToken(t *testing.T) {
+	g := newTestGinQQ(t, &Config{DisableTransactionLog: true}, func([]byte) {})
+	web := g.Group("/web")
+	web.Use(CSRF(&CSRFConfig{Session: func(c *Context) string { return c.GetHeader("X-Session") }}))
+	web.GET("/form", func(c *Context) { c.String(http.StatusOK, c.CSRFToken()) })
+	web.POST("/orders", func(c *Context) { c.Status(http.StatusOK) })
+
+	get := httptest.NewRequest(http.MethodGet, "/web/form", nil)
+	get.Header.Set("X-Session", "alice")
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, get)
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("cookie issued in session mode")
+	}
+	token := w.Body.String()
+
+	for session, want := range map[string]int{"alice": http.StatusOK, "bob": http.StatusForbidden} {
+		req := httptest.NewRequest(http.MethodPost, "/web/orders", nil)
+		req.Header.Set("X-Session", session)
+		req.Header.Set("X-CSRF-Token", token)
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("session %s: status %d, want %d", session, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package ginqq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrorCategory 错误分类。
+type ErrorCategory string
+
+const (
+	ErrorCategoryClient     ErrorCategory = "client"     // 调用方错误，如参数错误、余额不足
+	ErrorCategoryServer     ErrorCategory = "server"     // 服务端错误
+	ErrorCategoryDownstream ErrorCategory = "downstream" // 下游服务错误
+)
+
+// ErrorDefinition 业务错误码定义。
+type ErrorDefinition struct {
+	Code       string        `json:"code"`
+	HTTPStatus int           `json:"http_status"` // 默认 HTTP 状态码，默认 200
+	Category   ErrorCategory `json:"category"`
+	MessageZh  string        `json:"message_zh"` // 中文信息，未指定语言时使用
+	MessageEn  string        `json:"message_en"` // 英文信息，Accept-Language 优先英文时使用，为空时使用中文信息
+}
+
+// ErrorRegistry 业务错误码注册表，集中声明错误码，实例创建时检查重复注册，可导出为 JSON 供接口文档使用。
+type ErrorRegistry struct {
+	mu          sync.RWMutex
+	definitions map[string]*ErrorDefinition
+	errs        []error // 注册时发现的问题，由 Validate 返回
+}
+
+// DefaultErrorRegistry 默认错误码注册表，RegisterError 注册到此表，Config.ErrorRegistry 默认使用此表。
+var DefaultErrorRegistry = NewErrorRegistry()
+
+var (
+	ErrApiStandardViolation = RegisterError(ErrorDefinition{
+		Code: CodeApiStandardViolation, HTTPStatus: http.StatusBadRequest, Category: ErrorCategoryClient,
+		MessageZh: "调用不符合API规范", MessageEn: "request does not conform to API standard",
+	})
+	ErrInternal = RegisterError(ErrorDefinition{
+		Code: CodeInternalError, HTTPStatus: http.StatusInternalServerError, Category: ErrorCategoryServer,
+		MessageZh: "服务内部错误", MessageEn: "internal server error",
+	})
+)
+
+func NewErrorRegistry() *ErrorRegistry {
+	return &ErrorRegistry{definitions: make(map[string]*ErrorDefinition)}
+}
+
+// RegisterError 注册错误码到 DefaultErrorRegistry 。
+func RegisterError(def ErrorDefinition) *BizError {
+	return DefaultErrorRegistry.Register(def)
+}
+
+// Register 注册错误码，返回可直接用于 Context.Fail 的业务错误，响应信息按 Accept-Language 选择。
+// 返回的错误通常作为包级变量共享，使用时按注册的定义生成副本，修改其字段不生效，需调整时使用 WithStatus、WithRemark 。
+// 重复或不合法的定义不会 panic，由 Validate 在实例创建时统一报告。
+func (r *ErrorRegistry) Register(def ErrorDefinition) *BizError {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case def.Code == "":
+		r.errs = append(r.errs, fmt.Errorf("error code is required: %+v", def))
+	case r.definitions[def.Code] != nil:
+		r.errs = append(r.errs, fmt.Errorf("error code %q registered more than once", def.Code))
+	default:
+		r.definitions[def.Code] = &def
+	}
+	if def.MessageZh == "" {
+		r.errs = append(r.errs, fmt.Errorf("error code %q: MessageZh is required", def.Code))
+	}
+	switch def.Category {
+	case ErrorCategoryClient, ErrorCategoryServer, ErrorCategoryDownstream:
+	default:
+		r.errs = append(r.errs, fmt.Errorf("error code %q: unknown category %q", def.Code, def.Category))
+	}
+	return &BizError{Code: def.Code, Message: def.MessageZh, HTTPStatus: def.HTTPStatus, definition: &def, registered: true}
+}
+
+// Validate 返回注册过程中发现的重复或不合法的错误码定义。
+func (r *ErrorRegistry) Validate() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return errors.Join(r.errs...)
+}
+
+// Lookup 查找错误码定义。
+func (r *ErrorRegistry) Lookup(code string) (ErrorDefinition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if def, ok := r.definitions[code]; ok {
+		return *def, true
+	}
+	return ErrorDefinition{}, false
+}
+
+// Definitions 返回按错误码排序的全部定义。
+func (r *ErrorRegistry) Definitions() []ErrorDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	defs := make([]ErrorDefinition, 0, len(r.definitions))
+	for _, def := range r.definitions {
+		defs = append(defs, *def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Code < defs[j].Code })
+	return defs
+}
+
+// WriteJSON 以 JSON 数组导出全部定义，供接口文档平台导入。
+func (r *ErrorRegistry) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r.Definitions())
+}
+
+// Handler 返回导出全部定义的接口。
+func (r *ErrorRegistry) Handler() func(*Context) {
+	return func(c *Context) {
+		c.JSON(http.StatusOK, r.Definitions())
+	}
+}
+
+// message 按 Accept-Language 选择错误信息，英文优先且有英文信息时返回英文，否则返回中文。
+func (d *ErrorDefinition) message(acceptLanguage string) string {
+	if d.MessageEn != "" && preferredLanguage(acceptLanguage) == "en" {
+		return d.MessageEn
+	}
+	return d.MessageZh
+}
+
+// preferredLanguage 解析 Accept-Language，返回权重最高的受支持语言（zh 或 en），未指定时返回 zh 。
+func preferredLanguage(acceptLanguage string) string {
+	lang, best := "zh", 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if (primary == "zh" || primary == "en") && q > best {
+			lang, best = primary, q
+		}
+	}
+	return lang
+}
@@ -0,0 +1,73 @@
+package ginqq
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorRegistry(t *testing.T) {
+	r := NewErrorRegistry()
+	errBalance := r.Register(ErrorDefinition{
+		Code: "10001", Category: ErrorCategoryClient, MessageZh: "余额不足", MessageEn: "insufficient balance",
+	})
+	r.Register(ErrorDefinition{Code: "20001", HTTPStatus: http.StatusBadGateway, Category: ErrorCategoryDownstream, MessageZh: "支付渠道异常"})
+	if err := r.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	g := newTestGinQQ(t, &Config{ErrorRegistry: r}, func([]byte) {})
+	g.GET("/pay", func(c *Context) { c.Fail(errBalance.WithRemark("balance=0")) })
+	g.GET("/codes", r.Handler())
+	for acceptLanguage, want := range map[string]string{
+		"":                             "余额不足",
+		"en-US,en;q=0.9":               "insufficient balance",
+		"zh-CN,zh;q=0.9,en;q=0.8":      "余额不足",
+		"fr-FR, en;q=0.5, zh-TW;q=0.4": "insufficient balance",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/pay", nil)
+		req.Header.Set("Accept-Language", acceptLanguage)
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, req)
+		var resp Response
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code != "10001" || resp.Msg != want {
+			t.Errorf("Accept-Language %q: got %+v, want msg %q", acceptLanguage, resp, want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/codes", nil))
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{w.Body.String(), buf.String()} {
+		var defs []ErrorDefinition
+		if err := json.Unmarshal([]byte(body), &defs); err != nil {
+			t.Fatal(err)
+		}
+		if len(defs) != 2 || defs[0].Code != "10001" || defs[1].HTTPStatus != http.StatusBadGateway {
+			t.Errorf("unexpected definitions: %s", body)
+		}
+	}
+
+	// 重复或不合法的定义在实例创建时报告
+	r.Register(ErrorDefinition{Code: "10001", Category: ErrorCategoryClient, MessageZh: "重复"})
+	r.Register(ErrorDefinition{Code: "30001", Category: "unknown"})
+	_, err := newGinQQ(&Config{SvcCode: "A186010101", AppName: "ginqq_test", DisableProgramLog: true, ErrorRegistry: r})
+	for _, want := range []string{
+		`error code "10001" registered more than once`,
+		`error code "30001": MessageZh is required`,
+		`error code "30001": unknown category "unknown"`,
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error missing %q: %v", want, err)
+		}
+	}
+}
@@ -8,7 +8,9 @@ import (
 func main() {
 	r := gin.Default("A186010101", "channel07_ginqq")
 	r.POST("/hello", gin.MethodCode("I00101"), Hello)
-	r.Run(":8080")
+	if err := r.RunWithGracefulShutdown(":8080"); err != nil {
+		panic(err)
+	}
 
 }
 
@@ -1,15 +1,42 @@
 package ginqq
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 )
 
 type H gin.H
 
-// GinQQ 自定义框架结构体。
+// GinQQ 自定义框架结构体，同一进程中可创建多个实例（如业务服务与管理服务），配置、流水、监控、链路互相独立。
 type GinQQ struct {
 	*gin.Engine
 	Config *Config
+
+	metrics              *serverMetrics
+	httpClientMetrics    *clientMetrics
+	tracer               *tracer
+	transactionLogWriter *asyncWriter
+	transactionLogSink   *multiSink
+	transactionLogFields []transactionLogField // 内置及 TransactionLogConfig.Fields 中的字段提取器
+	masker               *masker
+	apiStandard          *apiStandardChecker
+	security             *securityFilter
+	signatureVerifier    *signatureVerifier
+	programLog           *programLog
+	logControl           *logControl
+	capture              *captureOptions
+	transport            http.RoundTripper // 增强后的出站传输层
+
+	mu     sync.Mutex
+	server *http.Server
 }
 
 // Group 返回自定义 RouterGroup 。
@@ -60,15 +87,267 @@ func Default(svcCode, appName string) *GinQQ {
 }
 
 func NewEngineWithConfig(config *Config) *GinQQ {
-	if err := config.init(); err != nil {
+	gq, err := newGinQQ(config)
+	if err != nil {
 		panic(err)
 	}
-	gq := &GinQQ{gin.New(), config}
+	if !config.DisableMetrics {
+		// 先于全局中间件注册，指标采集接口本身不计入监控、链路和流水
+		gq.Engine.GET(config.MetricsConfig.Path, convertToGinHandlers([]func(*Context){MetricsHandler()})...)
+	}
+	if !config.DisableTracing {
+		gq.Use(TracingServerMiddleware())
+	}
+	if !config.DisableMetrics {
+		gq.Use(MetricsServerMiddleware())
+	}
 	if !config.DisableTransactionLog {
 		gq.Use(DispatchTransactionLog)
 	}
-	if !config.DisableHttpClientEnhance {
-		HttpEnhance(config.HttpClientEnhanceConfig)
+	if config.SigningConfig != nil && !config.SigningConfig.DisableVerify {
+		gq.Use(SignatureVerifyMiddleware())
+	}
+	if !config.DisableApiStandardServer {
+		// 在流水、监控之后注册，被拦截的调用同样记录流水和监控
+		gq.Use(ApiStandardServerMiddleware())
+	}
+	if !config.DisableSecurity {
+		gq.Use(SecurityMiddleware())
+	}
+	if !config.DisableHttpClientEnhance && config.HttpClientEnhanceConfig.ReplaceDefaultTransport {
+		// 未关联入站请求的出站请求使用最后创建的实例的配置
+		http.DefaultTransport = gq.transport
 	}
 	return gq
 }
+
+// newGinQQ 根据配置创建实例及其流水、监控、链路组件。
+func newGinQQ(c *Config) (*GinQQ, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	g := &GinQQ{Engine: gin.New(), Config: c, logControl: newLogControl(c)}
+	// 关联请求与当前实例，Context.Engine 及框架中间件据此获取实例配置
+	g.Engine.Use(func(gc *gin.Context) {
+		gc.Set(xEngine, g)
+	})
+	if !c.DisableMasking {
+		m, err := newMasker(c.MaskingConfig)
+		if err != nil {
+			return nil, err
+		}
+		g.masker = m
+	}
+	if !c.DisableApiStandardServer {
+		g.apiStandard = newApiStandardChecker(c.ApiStandardServerConfig)
+	}
+	if c.SigningConfig != nil && !c.SigningConfig.DisableVerify {
+		g.signatureVerifier = newSignatureVerifier(c.SigningConfig)
+	}
+	if !c.DisableSecurity {
+		g.security = newSecurityFilter(c.SecurityConfig)
+	}
+	if c.TransactionLogConfig != nil {
+		g.capture = captureSettings(c.TransactionLogConfig)
+		g.transactionLogFields = newTransactionLogFields(c.TransactionLogConfig.Fields)
+		sink := newMultiSink(c.TransactionLogConfig.QueueSize, c.TransactionLogConfig.OverflowPolicy, c.TransactionLogConfig.Sinks...)
+		g.transactionLogSink = sink
+		g.transactionLogWriter = newAsyncWriter(
+			func(msg []byte) { _ = sink.Write(msg) },
+			c.TransactionLogConfig.QueueSize,
+			c.TransactionLogConfig.Workers,
+			c.TransactionLogConfig.OverflowPolicy,
+		)
+	}
+	if !c.DisableMetrics {
+		g.metrics = newServerMetrics(c)
+		g.httpClientMetrics = newClientMetrics(c, g.metrics.registry)
+		if g.transactionLogWriter != nil {
+			g.metrics.registerTransactionLogDropped(g.transactionLogWriter, g.transactionLogSink)
+		}
+	}
+	if !c.DisableTracing {
+		g.tracer = newTracer(c)
+		if g.metrics != nil {
+			g.metrics.registerSpansDropped(g.tracer)
+		}
+	}
+	if !c.DisableHttpClientEnhance {
+		g.transport = newEnhancedTransport(c.HttpClientEnhanceConfig, g)
+	}
+	if !c.DisableProgramLog {
+		g.programLog = newProgramLog(c, g.logControl)
+		if g.metrics != nil {
+			g.metrics.registerProgramLogDropped(g.programLog)
+		}
+	}
+	c.setCertLogger(g.Logger())
+	return g, nil
+}
+
+// HTTPClient 返回使用实例增强传输层的 http.Client，未关联入站请求的出站请求按当前实例的配置透传、签名、记录流水和监控，
+// 关联入站请求时使用 Context.HTTPClient 或 Context.RequestContext 。实例未开启 http 增强时返回使用原始传输层的 http.Client 。
+func (g *GinQQ) HTTPClient() *http.Client {
+	if g.transport == nil {
+		return &http.Client{Transport: originalDefaultTransport}
+	}
+	return &http.Client{Transport: g.transport}
+}
+
+// Logger 返回程序日志 logger，日志按级别写入程序日志文件，禁用程序日志时返回 logrus 标准 logger 。
+func (g *GinQQ) Logger() *logrus.Logger {
+	if g.programLog == nil {
+		return logrus.StandardLogger()
+	}
+	return g.programLog.logger
+}
+
+// TransactionLogDropped 返回因写入队列或输出队列已满而丢弃的流水日志数，包括输出自身（如 HTTPBatchSink）丢弃的流水。
+func (g *GinQQ) TransactionLogDropped() uint64 {
+	if g.transactionLogWriter == nil {
+		return 0
+	}
+	return g.transactionLogWriter.Dropped() + g.transactionLogSink.Dropped()
+}
+
+// SpansDropped 返回因导出队列已满而丢弃的 Span 数。
+func (g *GinQQ) SpansDropped() uint64 {
+	if g.tracer == nil {
+		return 0
+	}
+	return g.tracer.dropped.Load()
+}
+
+// ProgramLogDropped 返回因队列已满而丢弃的程序日志数。
+func (g *GinQQ) ProgramLogDropped() uint64 {
+	if g.programLog == nil {
+		return 0
+	}
+	return g.programLog.dropped()
+}
+
+// RunWithGracefulShutdown 启动 HTTP 服务，收到 SIGINT/SIGTERM 后在 Config.ShutdownTimeout 内优雅退出：
+// 停止接收新连接、等待处理中的请求完成、将待写入的流水日志全部落盘并关闭日志文件。
+// 地址解析规则与 gin.Engine.Run 一致，配置了 Config.ServerTLSConfig 时以 HTTPS 提供服务。
+func (g *GinQQ) RunWithGracefulShutdown(addr ...string) error {
+	g.mu.Lock()
+	g.server = &http.Server{Addr: resolveAddress(addr), Handler: g.Engine}
+	if g.Config.ServerTLSConfig != nil {
+		g.server.TLSConfig = g.Config.ServerTLSConfig.serverConfig()
+	}
+	server := g.server
+	g.mu.Unlock()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if server.TLSConfig != nil {
+			// 证书由 TLSConfig 提供，支持热更新
+			serveErr <- server.ListenAndServeTLS("", "")
+			return
+		}
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			_ = g.flush(context.Background())
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), g.Config.ShutdownTimeout)
+	defer cancel()
+	return g.Shutdown(shutdownCtx)
+}
+
+// Shutdown 优雅关闭服务：停止接收新连接并等待处理中的请求完成（仅对 RunWithGracefulShutdown 启动的服务生效），
+// 随后等待队列中的流水日志、链路数据写出并关闭日志文件，超过 ctx 截止时间时返回错误。
+func (g *GinQQ) Shutdown(ctx context.Context) error {
+	var errs []error
+
+	g.mu.Lock()
+	server := g.server
+	g.mu.Unlock()
+	if server != nil {
+		if err := server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown http server: %w", err))
+		}
+	}
+
+	if err := g.flush(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// flush 写出待处理的流水日志、链路数据并关闭日志文件。
+func (g *GinQQ) flush(ctx context.Context) error {
+	var errs []error
+
+	closeSinks := g.transactionLogSink != nil
+	if g.transactionLogWriter != nil {
+		done := make(chan struct{})
+		go func() {
+			g.transactionLogWriter.Close()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("flush transaction log: %w", ctx.Err()))
+			// 写入协程仍在写出流水，写完后再关闭输出，避免向已关闭的输出写入
+			closeSinks = false
+			go func() {
+				<-done
+				_ = g.transactionLogSink.Close()
+			}()
+		}
+	}
+
+	if g.tracer != nil {
+		if err := g.tracer.shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("flush tracing spans: %w", err))
+		}
+	}
+
+	if closeSinks {
+		closed := make(chan error, 1)
+		go func() { closed <- g.transactionLogSink.Close() }()
+		select {
+		case err := <-closed:
+			if err != nil {
+				errs = append(errs, fmt.Errorf("close transaction log sinks: %w", err))
+			}
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("close transaction log sinks: %w", ctx.Err()))
+		}
+	}
+
+	if g.programLog != nil {
+		if err := g.programLog.close(); err != nil {
+			errs = append(errs, fmt.Errorf("close program log files: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resolveAddress 解析监听地址，未指定时使用环境变量 PORT，默认 ":8080" 。
+func resolveAddress(addr []string) string {
+	switch len(addr) {
+	case 0:
+		if port := os.Getenv("PORT"); port != "" {
+			return ":" + port
+		}
+		return ":8080"
+	case 1:
+		return addr[0]
+	default:
+		panic("too many parameters")
+	}
+}
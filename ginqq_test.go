@@ -0,0 +1,178 @@
+package ginqq
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShutdownFlushesTransactionLog(t *testing.T) {
+	var written atomic.Int32
+	g := newTestGinQQ(t, &Config{ShutdownTimeout: time.Second}, func([]byte) {
+		time.Sleep(50 * time.Millisecond) // 模拟写盘缓慢
+		written.Add(1)
+	})
+	g.Use(DispatchTransactionLog)
+	started := make(chan struct{})
+	g.GET("/slow", func(c *Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.server = &http.Server{Handler: g.Engine}
+	go func() { _ = g.server.Serve(listener) }()
+
+	result := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		result <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.Config.ShutdownTimeout)
+	defer cancel()
+	if err := g.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-result; err != nil {
+		t.Errorf("in-flight request failed: %v", err)
+	}
+	if written.Load() != 1 {
+		t.Errorf("transaction log written %d times before Shutdown returned, want 1", written.Load())
+	}
+}
+
+// closeTrackingSink 记录关闭后是否仍有写入的流水输出。
+type closeTrackingSink struct {
+	release         chan struct{}
+	closed          chan struct{}
+	writeAfterClose atomic.Bool
+}
+
+func (s *closeTrackingSink) Write([]byte) error {
+	<-s.release
+	select {
+	case <-s.closed:
+		s.writeAfterClose.Store(true)
+	default:
+	}
+	return nil
+}
+
+func (s *closeTrackingSink) Close() error {
+	close(s.closed)
+	return nil
+}
+
+func TestShutdownTimeoutKeepsSinksOpen(t *testing.T) {
+	sink := &closeTrackingSink{release: make(chan struct{}), closed: make(chan struct{})}
+	g, err := newGinQQ(&Config{SvcCode: "A186010101", AppName: "ginqq_test", DisableMetrics: true, DisableTracing: true,
+		DisableProgramLog: true, TransactionLogConfig: &TransactionLogConfig{Sinks: []TransactionLogSink{sink}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		g.transactionLogWriter.Write([]byte(`{}`))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := g.Shutdown(ctx); err == nil {
+		t.Fatal("expected flush timeout")
+	}
+	select {
+	case <-sink.closed:
+		t.Fatal("sink closed while records were still being written")
+	default:
+	}
+
+	// 写入协程写完后关闭输出
+	close(sink.release)
+	select {
+	case <-sink.closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("sink not closed after the writer finished")
+	}
+	if sink.writeAfterClose.Load() {
+		t.Error("record written after the sink was closed")
+	}
+}
+
+// sinkFunc 测试用流水输出。
+type sinkFunc func([]byte)
+
+func (f sinkFunc) Write(record []byte) error {
+	f(record)
+	return nil
+}
+
+func (f sinkFunc) Close() error { return nil }
+
+// newTestGinQQ 创建测试实例，流水交由 write 处理，默认不开启监控、链路和程序日志，不替换 http.DefaultTransport 。
+func newTestGinQQ(t testing.TB, c *Config, write func([]byte)) *GinQQ {
+	gin.SetMode(gin.TestMode)
+	if c.SvcCode == "" {
+		c.SvcCode, c.AppName = "A186010101", "ginqq_test"
+	}
+	if c.MetricsConfig == nil {
+		c.DisableMetrics = true
+	}
+	if c.TracingConfig == nil {
+		c.DisableTracing = true
+	}
+	if c.ProgramLogConfig == nil {
+		c.DisableProgramLog = true
+	}
+	if c.TransactionLogConfig == nil {
+		c.TransactionLogConfig = &TransactionLogConfig{}
+	}
+	c.TransactionLogConfig.Sinks = []TransactionLogSink{sinkFunc(write)}
+	g, err := newGinQQ(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestMultipleEngines(t *testing.T) {
+	linesA, linesB := make(chan []byte, 1), make(chan []byte, 1)
+	a := newTestGinQQ(t, &Config{SvcCode: "A186010101", AppName: "business"}, func(msg []byte) { linesA <- msg })
+	b := newTestGinQQ(t, &Config{SvcCode: "B186010101", AppName: "admin"}, func(msg []byte) { linesB <- msg })
+	for _, g := range []*GinQQ{a, b} {
+		g.Use(DispatchTransactionLog)
+		g.GET("/svc", func(c *Context) { c.String(http.StatusOK, c.Config().SvcCode) })
+	}
+
+	for _, tt := range []struct {
+		engine *GinQQ
+		lines  chan []byte
+		want   string
+	}{{a, linesA, "A186010101"}, {b, linesB, "B186010101"}} {
+		w := httptest.NewRecorder()
+		tt.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/svc", nil))
+		if w.Body.String() != tt.want {
+			t.Errorf("Context.Config().SvcCode = %q, want %q", w.Body.String(), tt.want)
+		}
+		var log map[string]interface{}
+		if err := json.Unmarshal(<-tt.lines, &log); err != nil {
+			t.Fatal(err)
+		}
+		if log["tcode"] != tt.want {
+			t.Errorf("tcode = %v, want %v", log["tcode"], tt.want)
+		}
+	}
+}
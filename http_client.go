@@ -1,12 +1,20 @@
 package ginqq
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"log"
+	"net"
 	"net/http"
+	"strconv"
 	"time"
 )
 
+// originalDefaultTransport 被 HttpEnhance 替换前的 http.DefaultTransport，供框架内部请求（如链路导出）使用。
+var originalDefaultTransport = http.DefaultTransport
+
 // ChainBuilder 链式构建器，用于构建中间件链，
 type ChainBuilder struct {
 	middlewares []http.RoundTripper // 中间件列表
@@ -93,32 +101,121 @@ func (l *LoggingTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	return resp, err
 }
 
+// MetricsTripper 出站请求监控中间件，按目标主机、TCode（WithTCode 设置）、Method-Code 记录请求数、错误数和耗时，
+// 指标记录在入站请求所属实例，未关联入站请求时记录在 engine 。
+type MetricsTripper struct {
+	next   http.RoundTripper
+	engine *GinQQ
+}
+
+func NewMetricsTripper() *MetricsTripper {
+	return &MetricsTripper{}
+}
+
+func (m *MetricsTripper) SetNext(next http.RoundTripper) {
+	m.next = next
+}
+
+func (m *MetricsTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	engine := outboundEngine(req, m.engine)
+	if engine == nil || engine.httpClientMetrics == nil {
+		return m.next.RoundTrip(req)
+	}
+	metrics := engine.httpClientMetrics
+
+	host := req.URL.Host
+	tCode := tCodeFromContext(req.Context())
+	methodCode := req.Header.Get(XMethodCode)
+
+	start := time.Now()
+	resp, err := m.next.RoundTrip(req)
+	elapsed := float64(time.Since(start)) / float64(time.Millisecond)
+
+	status := "error"
+	if err != nil {
+		metrics.errors.WithLabelValues(host, tCode, methodCode, classifyTransportError(err)).Inc()
+	} else {
+		status = strconv.Itoa(resp.StatusCode)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			metrics.errors.WithLabelValues(host, tCode, methodCode, "non_2xx").Inc()
+		}
+	}
+	metrics.requests.WithLabelValues(host, tCode, methodCode, req.Method, status).Inc()
+	metrics.latency.WithLabelValues(host, tCode, methodCode).Observe(elapsed)
+	return resp, err
+}
+
+// classifyTransportError 将传输层错误归类为 timeout、tls、dial、other。
+func classifyTransportError(err error) string {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return "timeout"
+	}
+
+	var (
+		recordErr    tls.RecordHeaderError
+		verifyErr    *tls.CertificateVerificationError
+		alertErr     tls.AlertError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	if errors.As(err, &recordErr) || errors.As(err, &verifyErr) || errors.As(err, &alertErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return "tls"
+	}
+
+	var (
+		opErr  *net.OpError
+		dnsErr *net.DNSError
+	)
+	if errors.As(err, &dnsErr) || (errors.As(err, &opErr) && opErr.Op == "dial") {
+		return "dial"
+	}
+	return "other"
+}
+
+// NewHTTPClient 返回使用增强传输层的 http.Client，出站请求的透传、签名、流水、监控按关联的入站请求所属实例处理
+// （通过 Context.RequestContext 关联），不影响进程内其他 http.Client 。cfg 为 nil 时使用默认配置。
+func NewHTTPClient(cfg *HttpClientEnhanceConfig) (*http.Client, error) {
+	if cfg == nil {
+		cfg = &HttpClientEnhanceConfig{}
+	}
+	if err := cfg.initTLS(); err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: newEnhancedTransport(cfg, nil)}, nil
+}
+
+// HttpEnhance 将进程级的 http.DefaultTransport 替换为增强的传输层，会影响进程内所有使用默认传输层的库，
+// 优先使用 NewHTTPClient 或 GinQQ.HTTPClient 。证书配置无效时 panic 。
 func HttpEnhance(cfg *HttpClientEnhanceConfig) {
+	client, err := NewHTTPClient(cfg)
+	if err != nil {
+		panic(err)
+	}
+	http.DefaultTransport = client.Transport
+}
 
+// newEnhancedTransport 构建增强的传输层，engine 为未关联入站请求时使用的实例。
+func newEnhancedTransport(cfg *HttpClientEnhanceConfig, engine *GinQQ) http.RoundTripper {
 	base := cfg.Transport
 	if base == nil {
-		base = http.DefaultTransport
-	}
-	// 跳过证书认证
-	if !cfg.DisableSkipVerify {
-		if base.(*http.Transport).TLSClientConfig == nil {
-			base.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-		} else {
-			base.(*http.Transport).TLSClientConfig.InsecureSkipVerify = true
-		}
+		base = originalDefaultTransport
 	}
+	// 按证书配置复制后修改，避免影响其他实例共用的传输层
+	base = newTLSTransport(base, cfg)
 
 	// 注册中间件
 	var middlewares []http.RoundTripper
-	if true {
-		middlewares = append(middlewares, NewLoggingTripper())
-	}
-	if true {
-		middlewares = append(middlewares, NewLoggingTripper2())
+	middlewares = append(middlewares, &PropagationTripper{engine: engine})
+	middlewares = append(middlewares, &SigningTripper{engine: engine}) // 签名透传后的请求头
+	if !cfg.DisableTransactionLog {
+		middlewares = append(middlewares, &TransactionLogTripper{engine: engine})
 	}
+	middlewares = append(middlewares, &MetricsTripper{engine: engine})
 
-	// 强制替换默认传输层为增强的传输层
-	http.DefaultTransport = NewChainBuilder(base).
+	return NewChainBuilder(base).
 		Use(middlewares...).
 		Build()
 }
@@ -1,11 +1,430 @@
 package ginqq
 
 import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestHttpClient(t *testing.T) {
-	Default("Y122010101", "Y122")
-	http.Get("http://www.baidu.com")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.UserAgent()))
+	}))
+	defer server.Close()
+
+	original := http.DefaultTransport
+	defer func() { http.DefaultTransport = original }()
+	newEngine := func(enhance *HttpClientEnhanceConfig) *GinQQ {
+		r := NewEngineWithConfig(&Config{SvcCode: "Y122010101", AppName: "Y122", DisableProgramLog: true,
+			LogConfig: &LogConfig{LogDir: t.TempDir()}, HttpClientEnhanceConfig: enhance})
+		t.Cleanup(func() { _ = r.Shutdown(context.Background()) })
+		return r
+	}
+	r := newEngine(nil)
+	if http.DefaultTransport != original {
+		t.Fatal("http.DefaultTransport replaced without ReplaceDefaultTransport")
+	}
+	resp, err := r.HTTPClient().Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "Y122010101" {
+		t.Errorf("FCode %q, want Y122010101", body)
+	}
+
+	r = newEngine(&HttpClientEnhanceConfig{ReplaceDefaultTransport: true})
+	if http.DefaultTransport != r.transport {
+		t.Error("http.DefaultTransport not replaced with ReplaceDefaultTransport")
+	}
+}
+
+// wrappedTransport 包装 *http.Transport 的自定义传输层。
+type wrappedTransport struct {
+	inner *http.Transport
+}
+
+func (w *wrappedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return w.inner.RoundTrip(req)
+}
+
+// tlsWrappedTransport 实现 TLSTransport 的自定义传输层。
+type tlsWrappedTransport struct {
+	wrappedTransport
+}
+
+func (w *tlsWrappedTransport) WithTLSConfig(configure func(*tls.Config) *tls.Config) http.RoundTripper {
+	inner := w.inner.Clone()
+	inner.TLSClientConfig = configure(inner.TLSClientConfig)
+	return &tlsWrappedTransport{wrappedTransport{inner: inner}}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	inner := originalDefaultTransport.(*http.Transport)
+
+	client, err := NewHTTPClient(&HttpClientEnhanceConfig{Transport: &wrappedTransport{inner: inner}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(ts.URL); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Errorf("wrapped transport without TLS settings: err %v, want certificate error", err)
+	}
+
+	skipVerify := &TLSClientConfig{InsecureSkipVerify: true}
+	if _, err := NewHTTPClient(&HttpClientEnhanceConfig{Transport: &wrappedTransport{inner: inner}, TLS: skipVerify}); err == nil ||
+		!strings.Contains(err.Error(), "does not support TLS settings") {
+		t.Errorf("unsupported transport: err %v", err)
+	}
+
+	client, err = NewHTTPClient(&HttpClientEnhanceConfig{Transport: &tlsWrappedTransport{wrappedTransport{inner: inner}}, TLS: skipVerify})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatalf("TLSTransport: %v", err)
+	}
+	resp.Body.Close()
+	if inner.TLSClientConfig != nil && inner.TLSClientConfig.InsecureSkipVerify {
+		t.Error("base transport modified")
+	}
+}
+
+func TestMetricsTripper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	registry := prometheus.NewRegistry()
+	m := newClientMetrics(&Config{
+		SvcCode:       "A186010101",
+		AppName:       "ginqq_test",
+		MetricsConfig: &MetricsConfig{Buckets: []float64{100}},
+	}, registry)
+	tripper := &MetricsTripper{engine: &GinQQ{httpClientMetrics: m}}
+	client := &http.Client{Transport: NewChainBuilder(originalDefaultTransport).Use(tripper).Build()}
+
+	for _, path := range []string{"/ok", "/fail"} {
+		req, _ := http.NewRequestWithContext(WithTCode(context.Background(), "b186010101"), http.MethodGet, server.URL+path, nil)
+		req.Header.Set(XMethodCode, "I00101")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	if _, err := client.Get(closed.URL); err == nil {
+		t.Fatal("expected dial error")
+	}
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	if got := testutil.ToFloat64(m.requests.WithLabelValues(host, "B186010101", "I00101", "GET", "200")); got != 1 {
+		t.Errorf("requests{status=200} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.errors.WithLabelValues(host, "B186010101", "I00101", "non_2xx")); got != 1 {
+		t.Errorf("errors{type=non_2xx} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.errors.WithLabelValues(strings.TrimPrefix(closed.URL, "http://"), "", "", "dial")); got != 1 {
+		t.Errorf("errors{type=dial} = %v, want 1", got)
+	}
+}
+
+func TestPropagationTripper(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	received := make(chan http.Header, 2)
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Clone()
+	}))
+	defer downstream.Close()
+
+	client := &http.Client{Transport: NewChainBuilder(originalDefaultTransport).
+		Use(&PropagationTripper{engine: &GinQQ{Config: &Config{SvcCode: "A186010101"}}}).
+		Build()}
+
+	engine := gin.New()
+	engine.GET("/call", convertToGinHandlers([]func(*Context){MethodCode("I00101"), func(c *Context) {
+		req, _ := http.NewRequestWithContext(c.RequestContext(), http.MethodGet, downstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		// 直接使用 *Context 作为 context.Context
+		req, _ = http.NewRequestWithContext(c, http.MethodGet, downstream.URL, nil)
+		req.Header.Set(XMethodCode, "I00202")
+		resp, err = client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}})...)
+
+	req := httptest.NewRequest(http.MethodGet, "/call", nil)
+	req.Header.Set(XTraceID, "4bf92f3577b34da6a3ce929d0e0e4736")
+	req.Header.Set(XTransactionID, "parent")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	for _, wantMethodCode := range []string{"I00101", "I00202"} {
+		header := <-received
+		if got := header.Get(XTraceID); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("Trace-ID = %q", got)
+		}
+		if got := header.Get(XTransactionID); got == "" || got == "parent" {
+			t.Errorf("Transaction-ID = %q, want a new child id", got)
+		}
+		if got := header.Get(XFCode); got != "A186010101" {
+			t.Errorf("User-Agent = %q", got)
+		}
+		if got := header.Get(XMethodCode); got != wantMethodCode {
+			t.Errorf("Method-Code = %q, want %q", got, wantMethodCode)
+		}
+	}
+
+	// 直接传入 *gin.Context 时只读取请求信息，不在 Transport 的 goroutine 中写入 Keys
+	gc, _ := gin.CreateTestContext(httptest.NewRecorder())
+	gc.Request = httptest.NewRequest(http.MethodGet, "/call", nil)
+	gc.Request.Header.Set(XTraceID, "4bf92f3577b34da6a3ce929d0e0e4736")
+	values := outboundValuesFromContext(gc)
+	if values == nil || values.traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || values.transactionID != "" || len(gc.Keys) != 0 {
+		t.Errorf("unexpected values %+v, keys %v", values, gc.Keys)
+	}
+}
+
+func TestTransactionLogTripper(t *testing.T) {
+	lines := make(chan string, 8)
+	g := newTestGinQQ(t, &Config{}, func(msg []byte) { lines <- string(msg) })
+
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("T-Code") != "" {
+			t.Errorf("target service code sent as a header")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"code":"0000","phone":"13800001234"}`))
+	}))
+	defer downstream.Close()
+
+	client := &http.Client{Transport: NewChainBuilder(originalDefaultTransport).
+		Use(NewPropagationTripper(), NewTransactionLogTripper()).
+		Build()}
+
+	engine := g.Engine
+	engine.POST("/call", convertToGinHandlers([]func(*Context){func(c *Context) {
+		ctx := WithTCode(c.RequestContext(), "b186010101")
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, downstream.URL+"/api?x=1", strings.NewReader(`{"name":"ginqq"}`))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != `{"code":"0000","phone":"13800001234"}` {
+			t.Errorf("response body not preserved: %s", body)
+		}
+	}})...)
+
+	req := httptest.NewRequest(http.MethodPost, "/call", nil)
+	req.Header.Set(XTraceID, "4bf92f3577b34da6a3ce929d0e0e4736")
+	req.Header.Set(XTransactionID, "parent")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	var log map[string]interface{}
+	if err := json.Unmarshal([]byte(<-lines), &log); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"dialog_type":           "out",
+		"trace_id":              "4bf92f3577b34da6a3ce929d0e0e4736",
+		"parent_transaction_id": "parent",
+		"address":               downstream.URL + "/api",
+		"fcode":                 "A186010101",
+		"tcode":                 "B186010101",
+		"http_method":           "POST",
+		"request_payload":       `{"name":"ginqq","x":"1"}`,
+		"response_payload":      `{"code":"0000","phone":"138****1234"}`,
+		"response_code":         "0000",
+		"http_status_code":      "200",
+	} {
+		if got := log[key]; got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+	if id, _ := log["transaction_id"].(string); id == "" || id == "parent" {
+		t.Errorf("transaction_id = %q, want a new child id", id)
+	}
+
+	// 关闭报文记录时只记录查询参数，仍提取响应码
+	noPayload := newTestGinQQ(t, &Config{TransactionLogConfig: &TransactionLogConfig{DisablePayloadCapture: true}},
+		func(msg []byte) { lines <- string(msg) })
+	noPayload.Engine.POST("/call", engine.Routes()[0].HandlerFunc)
+	noPayload.Engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/call", nil))
+	log = nil
+	if err := json.Unmarshal([]byte(<-lines), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log["request_payload"] != `{"x":"1"}` || log["response_payload"] != "{}" || log["response_code"] != "0000" {
+		t.Errorf("unexpected outbound log without payload: %v", log)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type errorAfterReader struct {
+	data []byte
+	err  error
+}
+
+func (r *errorAfterReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// delayedReader 首次读取前等待 delay，模拟响应体传输耗时。
+type delayedReader struct {
+	delay time.Duration
+	io.Reader
+}
+
+func (r *delayedReader) Read(p []byte) (int, error) {
+	time.Sleep(r.delay)
+	r.delay = 0
+	return r.Reader.Read(p)
+}
+
+func TestTransactionLogTripperStreaming(t *testing.T) {
+	lines := make(chan string, 8)
+	g := newTestGinQQ(t, &Config{TransactionLogConfig: &TransactionLogConfig{RequestCaptureLimit: 16, ResponseCaptureLimit: 16}},
+		func(msg []byte) { lines <- string(msg) })
+	var response func() *http.Response
+	tripper := NewTransactionLogTripper()
+	tripper.engine = g
+	tripper.SetNext(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			_, _ = io.Copy(io.Discard, req.Body)
+			req.Body.Close()
+		}
+		return response(), nil
+	}))
+	client := &http.Client{Transport: tripper}
+	nextLog := func() map[string]interface{} {
+		var log map[string]interface{}
+		if err := json.Unmarshal([]byte(<-lines), &log); err != nil {
+			t.Fatal(err)
+		}
+		return log
+	}
+
+	// 响应体读取完毕后才写入流水，请求体、响应体按上限截断
+	response = func() *http.Response {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"application/json"}},
+			Body: io.NopCloser(strings.NewReader(`{"code":"0000","data":"` + strings.Repeat("a", 32) + `"}`))}
+	}
+	resp, err := client.Post("http://downstream/api", "text/plain", strings.NewReader(strings.Repeat("b", 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case line := <-lines:
+		t.Fatalf("transaction log written before the response body was read: %s", line)
+	case <-time.After(50 * time.Millisecond):
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if len(body) != 57 {
+		t.Errorf("response body not preserved: %s", body)
+	}
+	log := nextLog()
+	if log["request_payload"] != `{"_body":"bbbbbbbbbbbbbbbb...[truncated, 32 bytes total]"}` ||
+		log["response_payload"] != `"{\"code\":\"0000\",\"...[truncated, 57 bytes total]"` || log["response_code"] != "0000" {
+		t.Errorf("unexpected outbound log: %v", log)
+	}
+
+	// 二进制响应只记录占位说明，关闭未读完的响应体时写入流水
+	response = func() *http.Response {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"application/octet-stream"}},
+			Body: io.NopCloser(strings.NewReader(strings.Repeat("\x00", 64)))}
+	}
+	resp, err = client.Get("http://downstream/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.ReadFull(resp.Body, make([]byte, 8))
+	resp.Body.Close()
+	if log := nextLog(); log["response_payload"] != `"[binary content omitted, content-type: application/octet-stream, 8 bytes]"` {
+		t.Errorf("unexpected binary response payload: %v", log["response_payload"])
+	}
+
+	// 读取响应体出错时原样返回错误，并记录在流水中
+	response = func() *http.Response {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{},
+			Body: io.NopCloser(&errorAfterReader{data: []byte(`{"code":`), err: io.ErrUnexpectedEOF})}
+	}
+	resp, err = client.Get("http://downstream/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(resp.Body); err != io.ErrUnexpectedEOF {
+		t.Errorf("read error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	resp.Body.Close()
+	if log := nextLog(); log["response_remark"] != io.ErrUnexpectedEOF.Error() || log["http_status_code"] != "200" {
+		t.Errorf("unexpected outbound log on read error: %v", log)
+	}
+
+	// total_time 包含读取响应体的时间
+	response = func() *http.Response {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{},
+			Body: io.NopCloser(&delayedReader{delay: 50 * time.Millisecond, Reader: strings.NewReader(`{"code":"0000"}`)})}
+	}
+	resp, err = client.Get("http://downstream/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if log := nextLog(); log["total_time"].(float64) < 50 {
+		t.Errorf("total_time = %v, want body transfer included", log["total_time"])
+	}
+
+	// 响应体未关闭时，请求 context 结束时写入流水
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://downstream/api", nil)
+	if _, err = client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	select {
+	case line := <-lines:
+		if !strings.Contains(line, `"response_remark":"context canceled"`) {
+			t.Errorf("unexpected outbound log for unclosed body: %s", line)
+		}
+	case <-time.After(time.Second):
+		t.Error("transaction log not written for unclosed response body")
+	}
 }
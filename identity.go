@@ -0,0 +1,68 @@
+package ginqq
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// svcCodePattern 部门服务编码规则：平台编码（1位大写字母+3位数字）+ 系统编码（4位数字）+ 序号（2位数字）。
+	svcCodePattern = regexp.MustCompile(`^([A-Z]\d{3})(\d{4})(\d{2})$`)
+	// appNamePattern 应用名称规则：小写字母开头，由小写字母、数字组成，以单个下划线分隔。
+	appNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+)
+
+// ServiceIdentity 服务标识，由 SvcCode 和 AppName 解析，如 A186010101 为平台 A186、系统 0101、序号 01 。
+type ServiceIdentity struct {
+	SvcCode      string // 服务编码，如 A186010101
+	PlatformCode string // 平台编码，如 A186
+	SystemCode   string // 系统编码，如 0101
+	Sequence     string // 序号，如 01
+	AppName      string // 应用名称，如 order_api
+}
+
+// ParseServiceIdentity 解析并校验服务编码和应用名称，服务编码忽略大小写，应用名称中的中划线视为下划线。
+// 校验失败时返回的标识只包含规范化后的 SvcCode 和 AppName 。
+func ParseServiceIdentity(svcCode, appName string) (ServiceIdentity, error) {
+	return parseServiceIdentity(svcCode, appName, true)
+}
+
+// parseServiceIdentity 解析服务标识，required 为 false 时不校验未设置的项（如配置文件中未配置、由代码设置的项）。
+func parseServiceIdentity(svcCode, appName string, required bool) (ServiceIdentity, error) {
+	id := ServiceIdentity{
+		SvcCode: strings.ToUpper(strings.TrimSpace(svcCode)),
+		AppName: strings.ToLower(strings.ReplaceAll(strings.TrimSpace(appName), "-", "_")),
+	}
+
+	var errs []error
+	if id.SvcCode == "" {
+		if required {
+			errs = append(errs, errors.New(`parameter "SvcCode" is required`))
+		}
+	} else if m := svcCodePattern.FindStringSubmatch(id.SvcCode); m == nil {
+		errs = append(errs, fmt.Errorf(`parameter "SvcCode" %q is invalid, expected platform code (1 letter + 3 digits), `+
+			`system code (4 digits) and sequence (2 digits), e.g. "A186010101"`, svcCode))
+	} else {
+		id.PlatformCode, id.SystemCode, id.Sequence = m[1], m[2], m[3]
+	}
+	if id.AppName == "" {
+		if required {
+			errs = append(errs, errors.New(`parameter "AppName" is required`))
+		}
+	} else if !appNamePattern.MatchString(id.AppName) {
+		errs = append(errs, fmt.Errorf(`parameter "AppName" %q is invalid, expected lowercase letters and digits `+
+			`joined by single underscores, starting with a letter, e.g. "order_api"`, appName))
+	}
+	return id, errors.Join(errs...)
+}
+
+// Name 服务名称，用于日志目录、日志文件名、流水 app_name 及链路服务名，如 a186010101_order_api 。
+func (id ServiceIdentity) Name() string {
+	return strings.ToLower(id.SvcCode) + "_" + id.AppName
+}
+
+func (id ServiceIdentity) String() string {
+	return id.Name()
+}
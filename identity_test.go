@@ -0,0 +1,42 @@
+package ginqq
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseServiceIdentity(t *testing.T) {
+	id, err := ParseServiceIdentity(" a186010101 ", "Order-Api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ServiceIdentity{SvcCode: "A186010101", PlatformCode: "A186", SystemCode: "0101", Sequence: "01", AppName: "order_api"}
+	if id != want {
+		t.Errorf("got %+v, want %+v", id, want)
+	}
+	if id.Name() != "a186010101_order_api" {
+		t.Errorf("Name() = %q", id.Name())
+	}
+
+	for _, tt := range []struct {
+		svcCode, appName string
+		errs             []string
+	}{
+		{"", "", []string{`"SvcCode" is required`, `"AppName" is required`}},
+		{"A18", "app", []string{`"SvcCode" "A18" is invalid`}},
+		{"1186010101", "app", []string{`"SvcCode" "1186010101" is invalid`}},
+		{"A186010101", "9app", []string{`"AppName" "9app" is invalid`}},
+		{"A186010101", "order__api", []string{`"AppName" "order__api" is invalid`}},
+	} {
+		_, err := ParseServiceIdentity(tt.svcCode, tt.appName)
+		for _, want := range tt.errs {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("ParseServiceIdentity(%q, %q) error = %v, want %s", tt.svcCode, tt.appName, err, want)
+			}
+		}
+	}
+
+	if code := (&Config{SvcCode: "A18"}).GetPlayCode(); code != "" {
+		t.Errorf("GetPlayCode() on short code = %q, want empty", code)
+	}
+}
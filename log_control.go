@@ -0,0 +1,353 @@
+package ginqq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOverrideDuration = 10 * time.Minute
+	maxOverrideDuration     = 24 * time.Hour
+)
+
+// 临时配置的作用范围。
+const (
+	scopeGlobal         = "global"
+	scopeMethodCode     = "method_code"
+	scopePackage        = "package"
+	scopeCapturePayload = "capture_payload"
+)
+
+type overrideKey struct {
+	scope string
+	key   string
+}
+
+// logOverride 临时配置，到期后自动恢复。
+type logOverride struct {
+	level     logrus.Level
+	capture   bool
+	expiresAt time.Time
+	timer     *time.Timer
+}
+
+// logControl 运行时日志控制：程序日志全局级别、按 Method-Code 或包名的临时级别，
+// 以及按 Method-Code 临时开关流水请求体、响应体记录。
+type logControl struct {
+	mu        sync.RWMutex
+	base      logrus.Level // 配置的程序日志级别
+	capture   bool         // 配置的是否记录请求体、响应体
+	overrides map[overrideKey]*logOverride
+
+	loggers      []*logrus.Logger // 需同步级别的 logger
+	reportCaller []bool           // logger 原有的 ReportCaller 设置
+}
+
+func newLogControl(c *Config) *logControl {
+	lc := &logControl{
+		base:      logrus.InfoLevel,
+		capture:   c.TransactionLogConfig == nil || !c.TransactionLogConfig.DisablePayloadCapture,
+		overrides: make(map[overrideKey]*logOverride),
+	}
+	if c.ProgramLogConfig != nil {
+		lc.base, _ = logrus.ParseLevel(c.ProgramLogConfig.Level)
+	}
+	return lc
+}
+
+// attach 由 logControl 管理 logger 的级别。
+func (lc *logControl) attach(logger *logrus.Logger) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.loggers = append(lc.loggers, logger)
+	lc.reportCaller = append(lc.reportCaller, logger.ReportCaller)
+	logger.SetLevel(lc.base)
+}
+
+// detach 不再管理 logger 的级别，恢复其原有的 ReportCaller 设置。
+func (lc *logControl) detach(logger *logrus.Logger) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	for i, l := range lc.loggers {
+		if l == logger {
+			logger.SetReportCaller(lc.reportCaller[i])
+			lc.loggers = append(lc.loggers[:i], lc.loggers[i+1:]...)
+			lc.reportCaller = append(lc.reportCaller[:i], lc.reportCaller[i+1:]...)
+			return
+		}
+	}
+}
+
+// allows 判断日志是否达到生效级别：Method-Code 临时级别优先，其次包名临时级别，再次全局级别。
+func (lc *logControl) allows(entry *logrus.Entry) bool {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	return entry.Level <= lc.levelFor(entry)
+}
+
+func (lc *logControl) levelFor(entry *logrus.Entry) logrus.Level {
+	if len(lc.overrides) == 0 {
+		return lc.base
+	}
+	if methodCode, ok := entry.Data["method_code"].(string); ok && methodCode != "" {
+		if o, ok := lc.overrides[overrideKey{scopeMethodCode, strings.ToUpper(methodCode)}]; ok {
+			return o.level
+		}
+	}
+	if entry.HasCaller() {
+		// 匹配最长的包名前缀
+		pkg := callerPackage(entry.Caller.Function)
+		var matched *logOverride
+		var matchedLen int
+		for k, o := range lc.overrides {
+			if k.scope == scopePackage && len(k.key) > matchedLen && (pkg == k.key || strings.HasPrefix(pkg, k.key+"/")) {
+				matched, matchedLen = o, len(k.key)
+			}
+		}
+		if matched != nil {
+			return matched.level
+		}
+	}
+	if o, ok := lc.overrides[overrideKey{scopeGlobal, ""}]; ok {
+		return o.level
+	}
+	return lc.base
+}
+
+// capturePayload 判断指定 Method-Code 的流水是否记录请求体、响应体。
+func (lc *logControl) capturePayload(methodCode string) bool {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	if o, ok := lc.overrides[overrideKey{scopeCapturePayload, strings.ToUpper(methodCode)}]; ok {
+		return o.capture
+	}
+	return lc.capture
+}
+
+// mayCapturePayload 是否可能有流水需要记录请求体、响应体，用于跳过不必要的请求体、响应体包装。
+func (lc *logControl) mayCapturePayload() bool {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	if lc.capture {
+		return true
+	}
+	for k, o := range lc.overrides {
+		if k.scope == scopeCapturePayload && o.capture {
+			return true
+		}
+	}
+	return false
+}
+
+// set 设置临时配置，duration 后自动恢复。
+func (lc *logControl) set(key overrideKey, o *logOverride, duration time.Duration) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if old, ok := lc.overrides[key]; ok {
+		old.timer.Stop()
+	}
+	o.expiresAt = time.Now().Add(duration)
+	o.timer = time.AfterFunc(duration, func() {
+		lc.mu.Lock()
+		defer lc.mu.Unlock()
+		if lc.overrides[key] == o {
+			delete(lc.overrides, key)
+			lc.sync()
+		}
+	})
+	lc.overrides[key] = o
+	lc.sync()
+}
+
+// remove 恢复临时配置，key 为空时恢复 scope 下的全部配置，scope 为空时恢复全部。
+func (lc *logControl) remove(scope, key string) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	for k, o := range lc.overrides {
+		if scope == "" || (k.scope == scope && (key == "" || k.key == key)) {
+			o.timer.Stop()
+			delete(lc.overrides, k)
+		}
+	}
+	lc.sync()
+}
+
+// sync 将 logger 级别调整为所有生效级别中最详细的级别，由 allows 按范围过滤，调用方需持有写锁。
+func (lc *logControl) sync() {
+	level := lc.base
+	if o, ok := lc.overrides[overrideKey{scopeGlobal, ""}]; ok {
+		level = o.level
+	}
+	byPackage := false
+	for k, o := range lc.overrides {
+		if k.scope == scopeMethodCode || k.scope == scopePackage {
+			level = max(level, o.level)
+			byPackage = byPackage || k.scope == scopePackage
+		}
+	}
+	for i, logger := range lc.loggers {
+		logger.SetLevel(level)
+		logger.SetReportCaller(lc.reportCaller[i] || byPackage) // 按包名过滤需要调用方信息
+	}
+}
+
+// state 当前日志控制状态。
+func (lc *logControl) state() logControlState {
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	s := logControlState{Level: lc.base.String(), CapturePayload: lc.capture, Overrides: []logOverrideState{}}
+	for k, o := range lc.overrides {
+		item := logOverrideState{Scope: k.scope, Key: k.key, ExpiresAt: o.expiresAt.Format(time.RFC3339)}
+		if k.scope == scopeCapturePayload {
+			capture := o.capture
+			item.CapturePayload = &capture
+		} else {
+			item.Level = o.level.String()
+		}
+		s.Overrides = append(s.Overrides, item)
+	}
+	sort.Slice(s.Overrides, func(i, j int) bool {
+		if s.Overrides[i].Scope != s.Overrides[j].Scope {
+			return s.Overrides[i].Scope < s.Overrides[j].Scope
+		}
+		return s.Overrides[i].Key < s.Overrides[j].Key
+	})
+	return s
+}
+
+type logControlState struct {
+	Level          string             `json:"level"`           // 配置的程序日志级别
+	CapturePayload bool               `json:"capture_payload"` // 配置的是否记录请求体、响应体
+	Overrides      []logOverrideState `json:"overrides"`       // 生效中的临时配置
+}
+
+type logOverrideState struct {
+	Scope          string `json:"scope"` // global、method_code、package、capture_payload
+	Key            string `json:"key,omitempty"`
+	Level          string `json:"level,omitempty"`
+	CapturePayload *bool  `json:"capture_payload,omitempty"`
+	ExpiresAt      string `json:"expires_at"`
+}
+
+// logControlRequest 管理接口请求：
+//
+//	{"level": "debug", "duration": "10m"}                              临时调整全局级别
+//	{"level": "debug", "method_code": "I00101"}                        临时调整指定 Method-Code 的级别
+//	{"level": "trace", "package": "github.com/acme/order/repo"}        临时调整指定包（含子包）的级别
+//	{"capture_payload": true, "method_code": "I00101", "duration": "5m"} 临时开关指定 Method-Code 的流水请求体、响应体记录
+type logControlRequest struct {
+	Level          string `json:"level"`
+	MethodCode     string `json:"method_code"`
+	Package        string `json:"package"`
+	CapturePayload *bool  `json:"capture_payload"`
+	Duration       string `json:"duration"` // 生效时长，默认 10m，最长 24h
+}
+
+// LogControlHandler 运行时日志控制管理接口，可挂载在独立的管理实例上控制当前实例：
+//
+//	GET              查看当前级别及生效中的临时配置
+//	PUT/POST         设置临时配置（见 logControlRequest），到期后自动恢复
+//	DELETE           恢复全部临时配置，可通过查询参数 method_code、package 或 scope（global、method_code、package、capture_payload）只恢复指定配置
+//
+// 接口不做鉴权，需挂载在内部端口或由调用方添加鉴权中间件。
+func (g *GinQQ) LogControlHandler() func(*Context) {
+	return func(c *Context) {
+		switch c.Request.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			if err := g.applyLogControl(c); err != nil {
+				c.JSON(http.StatusBadRequest, H{"error": err.Error()})
+				return
+			}
+		case http.MethodDelete:
+			switch {
+			case c.Query("method_code") != "":
+				methodCode := strings.ToUpper(c.Query("method_code"))
+				g.logControl.remove(scopeMethodCode, methodCode)
+				g.logControl.remove(scopeCapturePayload, methodCode)
+			case c.Query("package") != "":
+				g.logControl.remove(scopePackage, c.Query("package"))
+			case c.Query("scope") != "":
+				g.logControl.remove(c.Query("scope"), "")
+			default:
+				g.logControl.remove("", "")
+			}
+		default:
+			c.AbortWithStatus(http.StatusMethodNotAllowed)
+			return
+		}
+		c.JSON(http.StatusOK, g.logControl.state())
+	}
+}
+
+func (g *GinQQ) applyLogControl(c *Context) error {
+	var req logControlRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	if req.Level == "" && req.CapturePayload == nil {
+		return errors.New(`"level" or "capture_payload" is required`)
+	}
+	if req.MethodCode != "" && req.Package != "" {
+		return errors.New(`"method_code" and "package" cannot be set together`)
+	}
+	duration := defaultOverrideDuration
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 || d > maxOverrideDuration {
+			return fmt.Errorf(`"duration" must be a positive duration up to %s, got %q`, maxOverrideDuration, req.Duration)
+		}
+		duration = d
+	}
+	methodCode := strings.ToUpper(strings.TrimSpace(req.MethodCode))
+
+	var level logrus.Level
+	if req.Level != "" {
+		if g.programLog == nil {
+			return errors.New("program log is disabled")
+		}
+		var err error
+		if level, err = logrus.ParseLevel(req.Level); err != nil {
+			return err
+		}
+	}
+	if req.CapturePayload != nil {
+		if methodCode == "" {
+			return errors.New(`"capture_payload" requires "method_code"`)
+		}
+		if g.transactionLogWriter == nil {
+			return errors.New("transaction log is disabled")
+		}
+	}
+
+	if req.Level != "" {
+		key := overrideKey{scope: scopeGlobal}
+		switch {
+		case methodCode != "":
+			key = overrideKey{scopeMethodCode, methodCode}
+		case req.Package != "":
+			key = overrideKey{scopePackage, req.Package}
+		}
+		g.logControl.set(key, &logOverride{level: level}, duration)
+	}
+	if req.CapturePayload != nil {
+		g.logControl.set(overrideKey{scopeCapturePayload, methodCode}, &logOverride{capture: *req.CapturePayload}, duration)
+	}
+	return nil
+}
+
+// callerPackage 由函数全名解析包路径，如 github.com/acme/order/repo.(*Repo).Find 返回 github.com/acme/order/repo 。
+func callerPackage(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
@@ -0,0 +1,121 @@
+package ginqq
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogControlHandler(t *testing.T) {
+	dir := t.TempDir()
+	lines := make(chan []byte, 2)
+	g := newTestGinQQ(t, &Config{
+		LogConfig:            &LogConfig{LogDir: dir},
+		ProgramLogConfig:     &ProgramLogConfig{Level: "info"},
+		TransactionLogConfig: &TransactionLogConfig{DisablePayloadCapture: true},
+	}, func(msg []byte) { lines <- msg })
+	g.Use(DispatchTransactionLog)
+	for _, methodCode := range []string{"I00101", "I00102"} {
+		g.POST("/"+methodCode, MethodCode(methodCode), func(c *Context) {
+			c.Logger().Debug("debug " + c.GetMethodCode())
+			c.Data(http.StatusOK, "application/json", []byte(`{"code":"0"}`))
+		})
+	}
+
+	// 管理接口挂载在独立实例上
+	admin := newTestGinQQ(t, &Config{AppName: "admin", SvcCode: "A186010101"}, func([]byte) {})
+	admin.GET("/admin/log", g.LogControlHandler())
+	admin.PUT("/admin/log", g.LogControlHandler())
+	admin.DELETE("/admin/log", g.LogControlHandler())
+	call := func(method, target, body string) (int, logControlState) {
+		w := httptest.NewRecorder()
+		admin.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		var state logControlState
+		_ = json.Unmarshal(w.Body.Bytes(), &state)
+		return w.Code, state
+	}
+
+	for _, body := range []string{
+		`{"level":"debug","method_code":"i00101","duration":"1h"}`,
+		`{"capture_payload":true,"method_code":"I00101"}`,
+	} {
+		if code, _ := call(http.MethodPut, "/admin/log", body); code != http.StatusOK {
+			t.Fatalf("PUT %s: status %d", body, code)
+		}
+	}
+	for _, body := range []string{
+		`{"capture_payload":true}`,
+		`{"level":"verbose"}`,
+		`{"level":"debug","duration":"48h"}`,
+	} {
+		if code, _ := call(http.MethodPut, "/admin/log", body); code != http.StatusBadRequest {
+			t.Errorf("PUT %s: status %d, want 400", body, code)
+		}
+	}
+	_, state := call(http.MethodGet, "/admin/log", "")
+	if state.Level != "info" || state.CapturePayload || len(state.Overrides) != 2 {
+		t.Fatalf("unexpected state: %+v", state)
+	}
+	if o := state.Overrides[1]; o.Scope != scopeMethodCode || o.Key != "I00101" || o.Level != "debug" {
+		t.Errorf("unexpected override: %+v", o)
+	}
+
+	payloads := make(map[string]string)
+	responseCodes := make(map[string]string)
+	for _, methodCode := range []string{"I00101", "I00102"} {
+		g.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/"+methodCode, strings.NewReader(`{"sku":"A1"}`)))
+		var transactionLog TransactionLog
+		if err := json.Unmarshal(<-lines, &transactionLog); err != nil {
+			t.Fatal(err)
+		}
+		payloads[methodCode] = transactionLog.RequestPayload + transactionLog.ResponsePayload
+		responseCodes[methodCode] = transactionLog.ResponseCode
+	}
+	if payloads["I00101"] != `{"sku":"A1"}{"code":"0"}` || payloads["I00102"] != "{}{}" {
+		t.Errorf("unexpected payloads: %v", payloads)
+	}
+	// 不记录响应体时仍提取响应码
+	if responseCodes["I00101"] != "0" || responseCodes["I00102"] != "0" {
+		t.Errorf("unexpected response codes: %v", responseCodes)
+	}
+
+	// 到期自动恢复
+	call(http.MethodPut, "/admin/log", `{"level":"trace","duration":"20ms"}`)
+	time.Sleep(100 * time.Millisecond)
+	if _, state := call(http.MethodGet, "/admin/log", ""); len(state.Overrides) != 2 {
+		t.Errorf("global override not reverted: %+v", state.Overrides)
+	}
+	if _, state := call(http.MethodDelete, "/admin/log?method_code=I00101", ""); len(state.Overrides) != 0 {
+		t.Errorf("overrides not removed: %+v", state.Overrides)
+	}
+
+	if err := g.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	name := "a186010101_ginqq_test"
+	content, err := os.ReadFile(filepath.Join(dir, name, "debug", name+"_code-debug.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "debug I00101") || strings.Contains(string(content), "debug I00102") {
+		t.Errorf("unexpected debug log:\n%s", content)
+	}
+}
+
+func TestCallerPackage(t *testing.T) {
+	for function, want := range map[string]string{
+		"github.com/acme/order/repo.(*Repo).Find": "github.com/acme/order/repo",
+		"github.com/acme/order.Handle.func1":      "github.com/acme/order",
+		"main.main":                               "main",
+	} {
+		if got := callerPackage(function); got != want {
+			t.Errorf("callerPackage(%q) = %q, want %q", function, got, want)
+		}
+	}
+}
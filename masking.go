@@ -0,0 +1,264 @@
+package ginqq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// 内置敏感信息正则，可用于 MaskRule.Pattern 。
+const (
+	PhonePattern    = `\b1[3-9]\d{9}\b`                  // 手机号
+	IDCardPattern   = `\b\d{6}(?:18|19|20)\d{9}[\dXx]\b` // 18位身份证号
+	BankCardPattern = `\b[1-9]\d{15,18}\b`               // 银行卡号，需配合 LuhnValid 校验，避免匹配订单号、时间戳
+)
+
+const maskRedacted = "******"
+
+// MaskStrategy 脱敏方式。
+type MaskStrategy int
+
+const (
+	MaskRedact  MaskStrategy = iota // 整体替换为 ******
+	MaskPartial                     // 保留首尾，中间替换为 *，如 138****1234
+	MaskHash                        // 替换为加盐 SHA-256 摘要，可用于关联同一数据但无法还原
+)
+
+// UnmarshalText 解析配置文件、环境变量中的脱敏方式：redact、partial、hash 。
+func (s *MaskStrategy) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "redact":
+		*s = MaskRedact
+	case "partial":
+		*s = MaskPartial
+	case "hash":
+		*s = MaskHash
+	default:
+		return fmt.Errorf("unknown mask strategy %q", text)
+	}
+	return nil
+}
+
+// MaskRule 脱敏规则，Keys、Headers、Pattern 至少设置一项。
+type MaskRule struct {
+	Keys     []string                // 按数据键名匹配，忽略大小写、空格、中划线和下划线，如 "phone_num" 可匹配 "phoneNum"
+	Headers  []string                // 按请求头/响应头名称匹配，忽略大小写
+	Pattern  string                  // 按正则匹配字符串中的内容，如 PhonePattern
+	Validate func(match string) bool // 校验 Pattern 匹配的内容，返回 false 时不脱敏，如 LuhnValid
+
+	Strategy   MaskStrategy
+	KeepPrefix int // MaskPartial 保留的前缀位数，默认 3
+	KeepSuffix int // MaskPartial 保留的后缀位数，默认 4
+}
+
+type MaskingConfig struct {
+	Rules               []MaskRule // 自定义规则，键名、请求头与默认规则重复时以自定义规则为准
+	DisableDefaultRules bool       // 禁用默认规则（鉴权请求头、密码/令牌类键名、手机号、身份证号、银行卡号）
+	HashSalt            string     // MaskHash 使用的盐值
+}
+
+// defaultMaskRules 默认脱敏规则。
+var defaultMaskRules = []MaskRule{
+	{Headers: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}, Strategy: MaskRedact},
+	{Keys: []string{"password", "passwd", "pwd", "token", "access_token", "refresh_token", "secret", "client_secret"}, Strategy: MaskRedact},
+	{Pattern: IDCardPattern, Strategy: MaskPartial, KeepPrefix: 3, KeepSuffix: 4},
+	{Pattern: BankCardPattern, Validate: LuhnValid, Strategy: MaskPartial, KeepPrefix: 4, KeepSuffix: 4},
+	{Pattern: PhonePattern, Strategy: MaskPartial, KeepPrefix: 3, KeepSuffix: 4},
+}
+
+type patternRule struct {
+	re   *regexp.Regexp
+	rule *MaskRule
+}
+
+// masker 按脱敏规则处理流水日志中的数据。
+type masker struct {
+	keys     map[string]*MaskRule // simplifyKey 后的键名
+	headers  map[string]*MaskRule // 规范化后的请求头名称
+	patterns []patternRule
+	salt     string
+}
+
+func newMasker(cfg *MaskingConfig) (*masker, error) {
+	m := &masker{
+		keys:    make(map[string]*MaskRule),
+		headers: make(map[string]*MaskRule),
+		salt:    cfg.HashSalt,
+	}
+
+	var rules []MaskRule
+	if !cfg.DisableDefaultRules {
+		rules = append(rules, defaultMaskRules...)
+	}
+	rules = append(rules, cfg.Rules...)
+
+	var errs []error
+	for i := range rules {
+		rule := &rules[i]
+		if len(rule.Keys) == 0 && len(rule.Headers) == 0 && rule.Pattern == "" {
+			errs = append(errs, fmt.Errorf("MaskingConfig.Rules: rule %d has no Keys, Headers or Pattern", i))
+			continue
+		}
+		if rule.Strategy < MaskRedact || rule.Strategy > MaskHash {
+			errs = append(errs, fmt.Errorf("MaskingConfig.Rules: rule %d has unknown strategy %d", i, rule.Strategy))
+			continue
+		}
+		if rule.Strategy == MaskPartial && rule.KeepPrefix == 0 && rule.KeepSuffix == 0 {
+			rule.KeepPrefix, rule.KeepSuffix = 3, 4
+		}
+		for _, key := range rule.Keys {
+			m.keys[simplifyKey(key)] = rule
+		}
+		for _, header := range rule.Headers {
+			m.headers[textproto.CanonicalMIMEHeaderKey(header)] = rule
+		}
+		if rule.Pattern != "" {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("MaskingConfig.Rules: rule %d: %w", i, err))
+				continue
+			}
+			m.patterns = append(m.patterns, patternRule{re: re, rule: rule})
+		}
+	}
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+	return m, nil
+}
+
+// mask 按规则处理单个值。
+func (m *masker) mask(rule *MaskRule, value string) string {
+	switch rule.Strategy {
+	case MaskPartial:
+		runes := []rune(value)
+		if len(runes) <= rule.KeepPrefix+rule.KeepSuffix {
+			return strings.Repeat("*", len(runes))
+		}
+		return string(runes[:rule.KeepPrefix]) +
+			strings.Repeat("*", len(runes)-rule.KeepPrefix-rule.KeepSuffix) +
+			string(runes[len(runes)-rule.KeepSuffix:])
+	case MaskHash:
+		sum := sha256.Sum256([]byte(m.salt + value))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	default:
+		return maskRedacted
+	}
+}
+
+// maskString 对字符串中匹配正则规则的内容脱敏。
+func (m *masker) maskString(s string) string {
+	for _, p := range m.patterns {
+		s = p.re.ReplaceAllStringFunc(s, func(match string) string {
+			if p.rule.Validate != nil && !p.rule.Validate(match) {
+				return match
+			}
+			return m.mask(p.rule, match)
+		})
+	}
+	return s
+}
+
+// LuhnValid 按 Luhn 算法校验数字串，用于识别银行卡号。
+func LuhnValid(number string) bool {
+	if len(number) < 2 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < len(number); i++ {
+		c := number[len(number)-1-i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		d := int(c - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// maskValue 递归处理 JSON 反序列化后的数据，命中键名规则的值整体脱敏，其余字符串按正则规则脱敏。
+func (m *masker) maskValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if rule, ok := m.keys[simplifyKey(key)]; ok {
+				v[key] = m.maskKeyValue(rule, item)
+			} else {
+				v[key] = m.maskValue(item)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = m.maskValue(item)
+		}
+		return v
+	case string:
+		return m.maskString(v)
+	case json.Number:
+		if masked := m.maskString(v.String()); masked != v.String() {
+			return masked
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func (m *masker) maskKeyValue(rule *MaskRule, value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return m.mask(rule, v)
+	case map[string]interface{}, []interface{}:
+		return maskRedacted // 对象整体隐藏
+	default:
+		return m.mask(rule, fmt.Sprintf("%v", v))
+	}
+}
+
+// maskJSON 处理序列化后的 JSON 数据，非 JSON 内容按字符串处理。
+func (m *masker) maskJSON(s string) string {
+	if s == "" || s == "{}" {
+		return s
+	}
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil || decoder.More() {
+		return m.maskString(s)
+	}
+	serialized, err := marshalNoEscape(m.maskValue(data))
+	if err != nil {
+		return m.maskString(s)
+	}
+	return serialized
+}
+
+// maskHeaders 处理序列化后的请求头/响应头（map[string]string）。
+func (m *masker) maskHeaders(s string) string {
+	var headers map[string]string
+	if err := json.Unmarshal([]byte(s), &headers); err != nil {
+		return m.maskString(s)
+	}
+	for name, value := range headers {
+		if rule, ok := m.headers[textproto.CanonicalMIMEHeaderKey(name)]; ok {
+			headers[name] = m.mask(rule, value)
+		} else {
+			headers[name] = m.maskString(value)
+		}
+	}
+	serialized, _ := marshalNoEscape(headers)
+	return serialized
+}
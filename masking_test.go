@@ -0,0 +1,66 @@
+package ginqq
+
+import "testing"
+
+func TestMasker(t *testing.T) {
+	m, err := newMasker(&MaskingConfig{
+		Rules: []MaskRule{
+			{Keys: []string{"customer_name"}, Strategy: MaskPartial, KeepPrefix: 1, KeepSuffix: 0},
+			{Keys: []string{"open_id"}, Strategy: MaskHash},
+			{Headers: []string{"x-session"}, Strategy: MaskRedact},
+		},
+		HashSalt: "salt",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, got, want string
+	}{
+		{
+			"payload",
+			m.maskJSON(`{"phoneNum":"13800001234","Password":"p@ss","customerName":"张三丰","openId":"o1","card":{"id":"110101199003071234","bank":6222020200112233446,"order":6222020200112233445},"amount":100}`),
+			`{"Password":"******","amount":100,"card":{"bank":"6222***********3446","id":"110***********1234","order":6222020200112233445},"customerName":"张**","openId":"sha256:6ca55dd0e7b76e42297c25bccf4536aa7dc4862b45896d05f0baddb66d49fa69","phoneNum":"138****1234"}`,
+		},
+		{
+			"nested token object",
+			m.maskJSON(`{"data":{"token":{"value":"abc"}}}`),
+			`{"data":{"token":"******"}}`,
+		},
+		{
+			"plain text",
+			m.maskJSON(`call 13800001234 now`),
+			`call 138****1234 now`,
+		},
+		{
+			"headers",
+			m.maskHeaders(`{"Authorization":"Bearer abc","X-Session":"s1","X-Phone":"13800001234","Accept":"*/*"}`),
+			`{"Accept":"*/*","Authorization":"******","X-Phone":"138****1234","X-Session":"******"}`,
+		},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// 流水的所有字符串字段均按正则规则脱敏
+	log := &TransactionLog{engine: &GinQQ{masker: m}, Address: "/users/13800001234", OrderID: "13800001234",
+		Tag: "card 6222020200112233446", TransactionID: "1729238400000123"}
+	log.mask()
+	if log.Address != "/users/138****1234" || log.OrderID != "138****1234" || log.Tag != "card 6222***********3446" ||
+		log.TransactionID != "1729238400000123" {
+		t.Errorf("unexpected masked log: %+v", log)
+	}
+}
+
+func TestMaskerInvalidRules(t *testing.T) {
+	_, err := newMasker(&MaskingConfig{Rules: []MaskRule{
+		{Strategy: MaskRedact},
+		{Pattern: "(", Strategy: MaskRedact},
+	}})
+	if err == nil {
+		t.Fatal("expected invalid rules to be rejected")
+	}
+}
@@ -0,0 +1,192 @@
+package ginqq
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var (
+	metricLabelPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+	// reservedMetricLabels 内置标签名，自定义标签不能与之重名。
+	reservedMetricLabels = map[string]bool{
+		"svc_code": true, "app_name": true, "platform_code": true,
+		"method_code": true, "method_name": true, "http_method": true, "route": true, "status": true,
+	}
+)
+
+// serverMetrics 服务端接口监控指标，统一注册到独立的 Registry 中，避免与业务自定义指标冲突。
+type serverMetrics struct {
+	registry *prometheus.Registry
+	handler  http.Handler // /metrics 输出
+
+	requests *prometheus.CounterVec   // 请求总数
+	inFlight *prometheus.GaugeVec     // 处理中的请求数
+	latency  *prometheus.HistogramVec // 请求耗时（毫秒）
+
+	constLabels  prometheus.Labels
+	customLabels []string // 自定义标签名（已排序）
+	customKeys   []string // 自定义标签值对应的 ctx key，与 customLabels 一一对应
+}
+
+// identityLabels 服务标识标签，所有指标共用。
+func identityLabels(id ServiceIdentity) prometheus.Labels {
+	return prometheus.Labels{"svc_code": id.SvcCode, "app_name": id.AppName, "platform_code": id.PlatformCode}
+}
+
+func newServerMetrics(c *Config) *serverMetrics {
+	m := &serverMetrics{registry: prometheus.NewRegistry()}
+
+	for label := range c.MetricsConfig.CustomLabels {
+		m.customLabels = append(m.customLabels, label)
+	}
+	sort.Strings(m.customLabels)
+	for _, label := range m.customLabels {
+		m.customKeys = append(m.customKeys, c.MetricsConfig.CustomLabels[label])
+	}
+
+	constLabels := identityLabels(c.Identity())
+	m.constLabels = constLabels
+	labels := append([]string{"method_code", "method_name", "http_method", "route", "status"}, m.customLabels...)
+
+	m.requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:        "http_server_requests_total",
+		Help:        "Total number of HTTP requests handled by the server.",
+		ConstLabels: constLabels,
+	}, labels)
+	m.inFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:        "http_server_requests_in_flight",
+		Help:        "Number of HTTP requests currently being handled by the server.",
+		ConstLabels: constLabels,
+	}, []string{"http_method", "route"})
+	m.latency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:        "http_server_request_duration_milliseconds",
+		Help:        "Latency of HTTP requests handled by the server in milliseconds.",
+		ConstLabels: constLabels,
+		Buckets:     c.MetricsConfig.Buckets,
+	}, labels)
+
+	m.registry.MustRegister(m.requests, m.inFlight, m.latency)
+	m.handler = promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	return m
+}
+
+// handle 采集单次请求的指标，作为中间件使用。
+func (m *serverMetrics) handle(c *Context) {
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched" // 未匹配路由时不使用原始路径，避免标签基数膨胀
+	}
+	httpMethod := c.Request.Method
+
+	inFlight := m.inFlight.WithLabelValues(httpMethod, route)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	start := time.Now()
+	c.Next()
+	elapsed := float64(time.Since(start)) / float64(time.Millisecond)
+
+	values := make([]string, 0, 5+len(m.customKeys))
+	values = append(values,
+		c.GetMethodCode(),
+		c.GetMethodName(),
+		httpMethod,
+		route,
+		strconv.Itoa(c.Writer.Status()),
+	)
+	for _, key := range m.customKeys {
+		values = append(values, customLabelValue(c, key))
+	}
+
+	m.requests.WithLabelValues(values...).Inc()
+	m.latency.WithLabelValues(values...).Observe(elapsed)
+}
+
+// customLabelValue 从 ctx 中获取自定义标签值，不存在时返回空字符串。
+func customLabelValue(c *Context, key string) string {
+	value, ok := c.Get(key)
+	if !ok || value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// registerTransactionLogDropped 注册流水日志丢弃数指标，包括写入队列、各输出队列已满时及输出自身丢弃的流水。
+func (m *serverMetrics) registerTransactionLogDropped(w *asyncWriter, sink *multiSink) {
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name:        "transaction_log_dropped_total",
+		Help:        "Total number of transaction log records dropped because the write queue or a sink queue was full, or by the sink itself.",
+		ConstLabels: m.constLabels,
+	}, func() float64 { return float64(w.Dropped() + sink.Dropped()) }))
+}
+
+// registerSpansDropped 注册 Span 丢弃数指标。
+func (m *serverMetrics) registerSpansDropped(t *tracer) {
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name:        "spans_dropped_total",
+		Help:        "Total number of spans dropped because the export queue was full.",
+		ConstLabels: m.constLabels,
+	}, func() float64 { return float64(t.dropped.Load()) }))
+}
+
+// registerProgramLogDropped 注册程序日志丢弃数指标。
+func (m *serverMetrics) registerProgramLogDropped(p *programLog) {
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name:        "program_log_dropped_total",
+		Help:        "Total number of program log entries dropped because the write queue was full.",
+		ConstLabels: m.constLabels,
+	}, func() float64 { return float64(p.dropped()) }))
+}
+
+// clientMetrics HttpEnhance 出站请求监控指标，与服务端指标共用 Registry 和桶配置。
+type clientMetrics struct {
+	requests *prometheus.CounterVec   // 出站请求总数
+	errors   *prometheus.CounterVec   // 出站请求错误数，按错误类型区分
+	latency  *prometheus.HistogramVec // 出站请求耗时（毫秒）
+}
+
+func newClientMetrics(c *Config, registry *prometheus.Registry) *clientMetrics {
+	constLabels := identityLabels(c.Identity())
+	m := &clientMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "http_client_requests_total",
+			Help:        "Total number of outbound HTTP requests.",
+			ConstLabels: constLabels,
+		}, []string{"host", "tcode", "method_code", "http_method", "status"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "http_client_request_errors_total",
+			Help:        "Total number of failed outbound HTTP requests by error type.",
+			ConstLabels: constLabels,
+		}, []string{"host", "tcode", "method_code", "type"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        "http_client_request_duration_milliseconds",
+			Help:        "Latency of outbound HTTP requests in milliseconds.",
+			ConstLabels: constLabels,
+			Buckets:     c.MetricsConfig.Buckets,
+		}, []string{"host", "tcode", "method_code"}),
+	}
+	registry.MustRegister(m.requests, m.errors, m.latency)
+	return m
+}
+
+// MetricsHandler 以 Prometheus 文本格式输出监控指标。
+func MetricsHandler() func(*Context) {
+	return func(c *Context) {
+		engine := c.Engine()
+		if engine == nil || engine.metrics == nil {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		engine.metrics.handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
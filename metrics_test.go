@@ -0,0 +1,45 @@
+package ginqq
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := newServerMetrics(&Config{
+		SvcCode: "A186010101",
+		AppName: "ginqq_test",
+		MetricsConfig: &MetricsConfig{
+			Buckets:      []float64{10, 100},
+			CustomLabels: map[string]string{"operate_type": "OPERATE_TYPE"},
+		},
+	})
+
+	engine := gin.New()
+	engine.Use(convertToGinHandlers([]func(*Context){m.handle})...)
+	engine.GET("/users/:id", convertToGinHandlers([]func(*Context){MethodCode("I00101"), func(c *Context) {
+		c.Set("OPERATE_TYPE", "query")
+		c.Status(http.StatusCreated)
+	}})...)
+	engine.GET("/metrics", func(c *gin.Context) { m.handler.ServeHTTP(c.Writer, c.Request) })
+
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		`http_server_requests_total{app_name="ginqq_test",http_method="GET",method_code="I00101",method_name="TestServerMetrics",operate_type="query",platform_code="A186",route="/users/:id",status="201",svc_code="A186010101"} 1`,
+		`http_server_request_duration_milliseconds_bucket{app_name="ginqq_test",http_method="GET",method_code="I00101",method_name="TestServerMetrics",operate_type="query",platform_code="A186",route="/users/:id",status="201",svc_code="A186010101",le="100"} 1`,
+		`http_server_requests_in_flight{app_name="ginqq_test",http_method="GET",platform_code="A186",route="/users/:id",svc_code="A186010101"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %s\n%s", want, body)
+		}
+	}
+}
@@ -2,10 +2,18 @@ package ginqq
 
 import "strings"
 
-// MethodCode 是一个中间件，用于设置接口编码。
+// MethodCode 是一个中间件，用于设置接口编码。开启服务端 API 规范校验时，调用方传入的 Method-Code 需与之一致。
 func MethodCode(I string) func(*Context) {
+	methodCode := strings.ToUpper(strings.TrimSpace(I))
 	return func(c *Context) {
-		c.Set(XMethodCode, strings.ToUpper(strings.TrimSpace(I)))
+		header := strings.TrimSpace(c.GetHeader(XMethodCode))
+		c.Set(XMethodCode, methodCode)
+		if header != "" && !strings.EqualFold(header, methodCode) && c.GetBool(xApiStandardChecked) {
+			violation := ApiStandardViolation{Header: XMethodCode, Reason: "mismatch", Value: header}
+			if c.Engine().apiStandard.reject(c, []ApiStandardViolation{violation}) {
+				return
+			}
+		}
 		c.Next()
 	}
 }
@@ -15,30 +23,66 @@ func TransactionLogMiddleware() func(*Context) {
 	return DispatchTransactionLog
 }
 
+// MetricsServerMiddleware 接口监控中间件，记录请求数、处理中请求数及请求耗时。
 func MetricsServerMiddleware() func(*Context) {
 	return func(ctx *Context) {
-		println("MetricsServerMiddleware")
-		ctx.Next()
+		engine := ctx.Engine()
+		if engine == nil || engine.metrics == nil {
+			ctx.Next()
+			return
+		}
+		engine.metrics.handle(ctx)
 	}
 }
 
+// TracingServerMiddleware 链路中间件，支持 W3C traceparent/tracestate 传播（兼容 Trace-ID），为每个请求创建服务端 Span 。
 func TracingServerMiddleware() func(*Context) {
 	return func(ctx *Context) {
-		println("TracingServerMiddleware")
-		ctx.Next()
+		engine := ctx.Engine()
+		if engine == nil || engine.tracer == nil {
+			ctx.Next()
+			return
+		}
+		engine.tracer.handle(ctx)
 	}
 }
 
+// ApiStandardServerMiddleware 服务端 API 规范校验中间件，记录缺少或格式错误的 Method-Code、FCode（User-Agent）、
+// Transaction-ID、Trace-ID 的调用，可补全的 Transaction-ID、Trace-ID 自动生成。
+// ApiStandardServerConfig.Enforce 时拦截不合规调用，返回标准响应格式。
 func ApiStandardServerMiddleware() func(*Context) {
 	return func(ctx *Context) {
-		println("ApiStandardServerMiddleware")
-		ctx.Next()
+		engine := ctx.Engine()
+		if engine == nil || engine.apiStandard == nil {
+			ctx.Next()
+			return
+		}
+		engine.apiStandard.handle(ctx)
 	}
 }
 
+// SecurityMiddleware 安全过滤中间件，按规则检查查询参数、表单、JSON 请求体及指定请求头中的 XSS、SQL 注入特征，
+// 命中时在程序日志及流水 security_event 中记录，SecurityConfig.Enforce 时以标准响应格式拒绝请求。
 func SecurityMiddleware() func(*Context) {
 	return func(ctx *Context) {
-		println("SecurityMiddleware")
-		ctx.Next()
+		engine := ctx.Engine()
+		if engine == nil || engine.security == nil {
+			ctx.Next()
+			return
+		}
+		engine.security.handle(ctx)
+	}
+}
+
+// SignatureVerifyMiddleware 服务间请求验签中间件，校验签名、时间偏差及随机串重放，
+// 通过后可由 Context.VerifiedFCode 获取调用方服务编码，未配置 SigningConfig 或设置 DisableVerify 时不校验。
+func SignatureVerifyMiddleware() func(*Context) {
+	return func(ctx *Context) {
+		engine := ctx.Engine()
+		if engine == nil || engine.signatureVerifier == nil {
+			ctx.Next()
+			return
+		}
+		engine.signatureVerifier.handle(ctx)
 	}
 }
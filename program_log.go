@@ -0,0 +1,232 @@
+package ginqq
+
+import (
+	"bytes"
+	lumberjack "github.com/DeRuina/timberjack"
+	"github.com/sirupsen/logrus"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// programLog 程序日志，按级别写入部门日志规范的文件：
+//
+//	<LogDir>/<svc>_<app>/trace/<svc>_<app>_trace-trace.log
+//	<LogDir>/<svc>_<app>/debug/<svc>_<app>_code-debug.log
+//	<LogDir>/<svc>_<app>/<svc>_<app>_code-{info,warning,error,fatal}.log
+//
+// Fatal、Panic 级别同步写入 fatal 文件，避免进程退出前丢失，其他级别异步写入。
+type programLog struct {
+	logger  *logrus.Logger
+	hook    *LevelHook
+	files   []*lumberjack.Logger
+	async   []*asyncWriter
+	control *logControl
+}
+
+// standardLogger 记录接管 logrus 标准 logger 的程序日志及接管前的设置，每个进程只允许一个实例接管。
+var standardLogger struct {
+	sync.Mutex
+	owner  *programLog
+	output io.Writer
+	level  logrus.Level
+}
+
+// 日志级别由 logControl 管理，可运行时调整。
+func newProgramLog(c *Config, control *logControl) *programLog {
+	cfg := c.ProgramLogConfig
+	p := &programLog{control: control}
+
+	newFile := func(dir, suffix string) *lumberjack.Logger {
+		file := &lumberjack.Logger{
+			Filename:         c.logFilename(dir, suffix),
+			MaxSize:          c.LogConfig.MaxSize,
+			MaxAge:           c.LogConfig.MaxAge,
+			MaxBackups:       c.LogConfig.MaxBackups,
+			LocalTime:        c.LogConfig.LocalTime,
+			Compress:         c.LogConfig.Compress,
+			RotationInterval: c.LogConfig.RotationInterval,
+		}
+		p.files = append(p.files, file)
+		return file
+	}
+	newAsync := func(file io.Writer) io.Writer {
+		w := newAsyncWriter(func(msg []byte) { _, _ = file.Write(msg) }, cfg.QueueSize, 1, cfg.OverflowPolicy)
+		p.async = append(p.async, w)
+		return &asyncLogWriter{w}
+	}
+
+	fatal := newFile("", "code-"+logrus.FatalLevel.String())
+	writers := map[logrus.Level]io.Writer{
+		logrus.TraceLevel: newAsync(newFile("trace", "trace-trace")),
+		logrus.DebugLevel: newAsync(newFile("debug", "code-debug")),
+		logrus.PanicLevel: fatal,
+		logrus.FatalLevel: fatal,
+	}
+	for _, l := range []logrus.Level{logrus.InfoLevel, logrus.WarnLevel, logrus.ErrorLevel} {
+		writers[l] = newAsync(newFile("", "code-"+l.String()))
+	}
+	p.hook = &LevelHook{writers: writers, formatter: NewJSONLineFormatter(c.Identity()), filter: control.allows}
+
+	p.logger = logrus.New()
+	p.logger.SetOutput(io.Discard)
+	p.logger.AddHook(p.hook)
+	control.attach(p.logger)
+
+	if cfg.HookStandardLogger && !p.hookStandardLogger() {
+		p.logger.Warn("logrus standard logger is already hooked by another ginqq instance")
+	}
+	return p
+}
+
+// hookStandardLogger 接管 logrus 标准 logger，已被其他实例接管时返回 false 。
+func (p *programLog) hookStandardLogger() bool {
+	standardLogger.Lock()
+	defer standardLogger.Unlock()
+	if standardLogger.owner != nil {
+		return false
+	}
+	std := logrus.StandardLogger()
+	standardLogger.owner, standardLogger.output, standardLogger.level = p, std.Out, std.GetLevel()
+	std.AddHook(p.hook)
+	std.SetOutput(io.Discard)
+	p.control.attach(std)
+	return true
+}
+
+// unhookStandardLogger 移除标准 logger 上的程序日志 hook，恢复接管前的输出和级别。
+func (p *programLog) unhookStandardLogger() {
+	standardLogger.Lock()
+	defer standardLogger.Unlock()
+	if standardLogger.owner != p {
+		return
+	}
+	std := logrus.StandardLogger()
+	p.control.detach(std)
+	hooks := make(logrus.LevelHooks)
+	for level, levelHooks := range std.Hooks {
+		for _, hook := range levelHooks {
+			if hook != p.hook {
+				hooks[level] = append(hooks[level], hook)
+			}
+		}
+	}
+	std.ReplaceHooks(hooks)
+	std.SetOutput(standardLogger.output)
+	std.SetLevel(standardLogger.level)
+	standardLogger.owner, standardLogger.output = nil, nil
+}
+
+// dropped 返回因队列已满而丢弃的日志数。
+func (p *programLog) dropped() uint64 {
+	var dropped uint64
+	for _, w := range p.async {
+		dropped += w.Dropped()
+	}
+	return dropped
+}
+
+// close 恢复标准 logger，写出队列中的日志并关闭日志文件。
+func (p *programLog) close() error {
+	p.unhookStandardLogger()
+	for _, w := range p.async {
+		w.Close()
+	}
+	var err error
+	for _, file := range p.files {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// asyncLogWriter 将日志提交到异步写入队列。
+type asyncLogWriter struct {
+	w *asyncWriter
+}
+
+func (w *asyncLogWriter) Write(p []byte) (int, error) {
+	w.w.Write(bytes.Clone(p)) // 格式化器可能复用缓冲区
+	return len(p), nil
+}
+
+// JSONLineFormatter 程序日志 JSON 行格式，每行包含日志时间、级别、服务标识、主机信息、消息及 logrus 字段。
+type JSONLineFormatter struct {
+	identity ServiceIdentity
+}
+
+func NewJSONLineFormatter(identity ServiceIdentity) *JSONLineFormatter {
+	return &JSONLineFormatter{identity: identity}
+}
+
+func (f *JSONLineFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	data := make(map[string]interface{}, len(entry.Data)+8)
+	for k, v := range entry.Data {
+		if err, ok := v.(error); ok {
+			v = err.Error() // error 序列化为 {}，记录错误信息
+		}
+		data[k] = v
+	}
+	host := localHost()
+	data["log_time"] = entry.Time.Format("2006-01-02 15:04:05.000")
+	data["level"] = levelName(entry.Level)
+	data["svc_code"] = f.identity.SvcCode
+	data["app_name"] = f.identity.AppName
+	data["host_ip"] = host.ip
+	data["hostname"] = host.name
+	data["message"] = entry.Message
+	if entry.HasCaller() {
+		data["caller"] = entry.Caller.File + ":" + strconv.Itoa(entry.Caller.Line)
+		data["func"] = entry.Caller.Function
+	}
+
+	serialized, err := marshalNoEscape(data)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(serialized), '\n'), nil
+}
+
+// levelName 日志级别名称，与流水日志 level 字段一致使用大写。
+func levelName(level logrus.Level) string {
+	if level == logrus.WarnLevel {
+		return "WARN"
+	}
+	b, _ := level.MarshalText()
+	return string(bytes.ToUpper(b))
+}
+
+type PlainFormatter struct{}
+
+func (f *PlainFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	return []byte(entry.Message + "\n"), nil
+}
+
+// LevelHook 分发不同级别的日志到不同文件
+type LevelHook struct {
+	writers   map[logrus.Level]io.Writer
+	formatter logrus.Formatter
+	filter    func(*logrus.Entry) bool // 按日志范围过滤，为空时不过滤
+}
+
+func (h *LevelHook) Levels() []logrus.Level {
+	levels := make([]logrus.Level, 0, len(h.writers))
+	for level := range h.writers {
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+func (h *LevelHook) Fire(entry *logrus.Entry) error {
+	writer, ok := h.writers[entry.Level]
+	if !ok || (h.filter != nil && !h.filter(entry)) {
+		return nil
+	}
+	msg, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(msg)
+	return err
+}
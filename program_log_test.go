@@ -0,0 +1,150 @@
+package ginqq
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProgramLog(t *testing.T) {
+	dir := t.TempDir()
+	hooks := len(logrus.StandardLogger().Hooks[logrus.InfoLevel])
+	g := newTestGinQQ(t, &Config{
+		LogConfig:        &LogConfig{LogDir: dir},
+		ProgramLogConfig: &ProgramLogConfig{Level: "debug"},
+	}, func([]byte) {})
+	if len(logrus.StandardLogger().Hooks[logrus.InfoLevel]) != hooks {
+		t.Error("standard logger hooked by default")
+	}
+	if g.Config.ProgramLogConfig.OverflowPolicy != OverflowDropOldest {
+		t.Errorf("default overflow policy = %s, want drop-oldest", g.Config.ProgramLogConfig.OverflowPolicy)
+	}
+
+	g.Logger().WithField("order_id", "1001").Info("order created")
+	g.Logger().Debug("debug detail")
+	g.Logger().Trace("below level")
+	if err := g.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	name := "a186010101_ginqq_test"
+	content, err := os.ReadFile(filepath.Join(dir, name, name+"_code-info.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line map[string]interface{}
+	if err := json.Unmarshal(content, &line); err != nil {
+		t.Fatalf("invalid json line %q: %v", content, err)
+	}
+	for key, want := range map[string]string{
+		"level":    "INFO",
+		"message":  "order created",
+		"order_id": "1001",
+		"svc_code": "A186010101",
+		"app_name": "ginqq_test",
+	} {
+		if line[key] != want {
+			t.Errorf("%s = %v, want %v", key, line[key], want)
+		}
+	}
+	if _, ok := line["hostname"]; !ok {
+		t.Error("hostname missing")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, name, "debug", name+"_code-debug.log")); err != nil {
+		t.Errorf("debug log not written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, name, "trace", name+"_trace-trace.log")); !os.IsNotExist(err) {
+		t.Errorf("trace log written below configured level: %v", err)
+	}
+}
+
+func TestContextLogger(t *testing.T) {
+	dir := t.TempDir()
+	lines := make(chan []byte, 1)
+	g := newTestGinQQ(t, &Config{
+		LogConfig:        &LogConfig{LogDir: dir},
+		ProgramLogConfig: &ProgramLogConfig{},
+	}, func(msg []byte) { lines <- msg })
+	g.Use(DispatchTransactionLog)
+	g.GET("/orders", MethodCode("I00101"), func(c *Context) {
+		c.Logger().Warn("stock low")
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set(XTraceID, "4bf92f3577b34da6a3ce929d0e0e4736")
+	req.Header.Set(XFCode, "b186010101")
+	g.ServeHTTP(httptest.NewRecorder(), req)
+	if err := g.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var transactionLog map[string]interface{}
+	if err := json.Unmarshal(<-lines, &transactionLog); err != nil {
+		t.Fatal(err)
+	}
+	name := "a186010101_ginqq_test"
+	content, err := os.ReadFile(filepath.Join(dir, name, name+"_code-warning.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line map[string]interface{}
+	if err := json.Unmarshal(content, &line); err != nil {
+		t.Fatalf("invalid json line %q: %v", content, err)
+	}
+	for key, want := range map[string]interface{}{
+		"level":          "WARN",
+		"message":        "stock low",
+		"trace_id":       "4bf92f3577b34da6a3ce929d0e0e4736",
+		"transaction_id": transactionLog["transaction_id"],
+		"method_code":    "I00101",
+		"method_name":    "TestContextLogger",
+		"fcode":          "B186010101",
+	} {
+		if line[key] != want {
+			t.Errorf("%s = %v, want %v", key, line[key], want)
+		}
+	}
+}
+
+func TestProgramLogHookStandardLogger(t *testing.T) {
+	std := logrus.StandardLogger()
+	output, level, hooks := std.Out, std.GetLevel(), len(std.Hooks[logrus.InfoLevel])
+	dir := t.TempDir()
+	newEngine := func(app string) *GinQQ {
+		return newTestGinQQ(t, &Config{
+			SvcCode:          "A186010101",
+			AppName:          app,
+			LogConfig:        &LogConfig{LogDir: dir},
+			ProgramLogConfig: &ProgramLogConfig{Level: "info", HookStandardLogger: true},
+		}, func([]byte) {})
+	}
+	first, second := newEngine("first"), newEngine("second")
+	if len(std.Hooks[logrus.InfoLevel]) != hooks+1 {
+		t.Fatalf("standard logger hooks = %d, want %d", len(std.Hooks[logrus.InfoLevel]), hooks+1)
+	}
+	logrus.Info("from standard logger")
+
+	for _, g := range []*GinQQ{second, first} {
+		if err := g.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if std.Out != output || std.GetLevel() != level || len(std.Hooks[logrus.InfoLevel]) != hooks {
+		t.Error("standard logger not restored on shutdown")
+	}
+	for app, want := range map[string]bool{"first": true, "second": false} {
+		name := "a186010101_" + app
+		content, _ := os.ReadFile(filepath.Join(dir, name, name+"_code-info.log"))
+		if got := strings.Contains(string(content), "from standard logger"); got != want {
+			t.Errorf("%s: standard logger entry written = %v, want %v", app, got, want)
+		}
+	}
+}
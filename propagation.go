@@ -0,0 +1,158 @@
+package ginqq
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// outboundKey context.Context 中保存入站请求上下文信息的 key 。
+type outboundKey struct{}
+
+// tCodeKey context.Context 中保存出站请求目标服务编码的 key 。
+type tCodeKey struct{}
+
+// WithTCode 返回携带出站请求目标服务编码（TCode）的 context，用于外部流水 tcode 及出站指标的 tcode 标签，
+// 目标服务编码只在本地使用，不作为请求头发送。
+func WithTCode(ctx context.Context, tCode string) context.Context {
+	return context.WithValue(ctx, tCodeKey{}, strings.ToUpper(tCode))
+}
+
+// tCodeFromContext 返回 WithTCode 设置的目标服务编码，未设置时返回空。
+func tCodeFromContext(ctx context.Context) string {
+	tCode, _ := ctx.Value(tCodeKey{}).(string)
+	return tCode
+}
+
+// outboundValues 出站请求需要从入站请求继承的上下文信息。
+type outboundValues struct {
+	traceID       string
+	transactionID string
+	methodCode    string
+	span          *Span
+	engine        *GinQQ
+}
+
+func newOutboundValues(c *Context) *outboundValues {
+	return &outboundValues{
+		traceID:       c.GetTraceID(),
+		transactionID: c.GetTransactionID(),
+		methodCode:    c.GetMethodCode(),
+		span:          c.GetSpan(),
+		engine:        c.Engine(),
+	}
+}
+
+// outboundValuesFromContext 获取出站请求关联的入站请求信息，
+// 支持 Context.RequestContext 返回的 context，以及直接传入的 *gin.Context / *Context 。
+func outboundValuesFromContext(ctx context.Context) *outboundValues {
+	if values, ok := ctx.Value(outboundKey{}).(*outboundValues); ok {
+		return values
+	}
+	if gc, ok := ctx.Value(gin.ContextKey).(*gin.Context); ok {
+		return peekOutboundValues(Wrap(gc))
+	}
+	return nil
+}
+
+// peekOutboundValues 只读获取入站请求信息，不生成、不写入链路ID及流水号，
+// 用于在 Transport 的 goroutine 中读取直接传入的 *gin.Context，避免与请求处理并发写入 Keys 。
+func peekOutboundValues(c *Context) *outboundValues {
+	traceID := c.GetString(XTraceID)
+	if traceID == "" {
+		traceID = c.headerTraceID()
+	}
+	transactionID := c.GetString(XTransactionID)
+	if transactionID == "" {
+		transactionID = c.GetHeader(XTransactionID)
+	}
+	return &outboundValues{
+		traceID:       traceID,
+		transactionID: transactionID,
+		methodCode:    c.GetMethodCode(),
+		span:          c.GetSpan(),
+		engine:        c.Engine(),
+	}
+}
+
+// outboundEngine 返回出站请求所属的 GinQQ 实例，优先使用关联的入站请求所属实例，其次使用 fallback 。
+func outboundEngine(req *http.Request, fallback *GinQQ) *GinQQ {
+	if values := outboundValuesFromContext(req.Context()); values != nil && values.engine != nil {
+		return values.engine
+	}
+	return fallback
+}
+
+// RequestContext 返回携带当前请求链路信息的 context.Context，
+// 用于 http.NewRequestWithContext 发起出站请求，HttpEnhance 会自动透传 Trace-ID、Transaction-ID 等请求头。
+func (c *Context) RequestContext() context.Context {
+	return context.WithValue(c.Request.Context(), outboundKey{}, newOutboundValues(c))
+}
+
+// HTTPClient 返回关联当前请求的 http.Client，发出的请求自动透传链路信息。
+func (c *Context) HTTPClient() *http.Client {
+	return &http.Client{Transport: &contextTripper{values: newOutboundValues(c)}}
+}
+
+// contextTripper 为未携带入站请求信息的出站请求补充上下文，再交由所属实例增强后的传输层处理，
+// 实例未开启 http 增强时使用 http.DefaultTransport 。
+type contextTripper struct {
+	values *outboundValues
+}
+
+func (t *contextTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, ok := req.Context().Value(outboundKey{}).(*outboundValues); !ok {
+		req = req.WithContext(context.WithValue(req.Context(), outboundKey{}, t.values))
+	}
+	if t.values.engine != nil && t.values.engine.transport != nil {
+		return t.values.engine.transport.RoundTrip(req)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// PropagationTripper 出站请求头透传中间件，注入 Trace-ID、traceparent、子 Transaction-ID、
+// FCode（User-Agent）及当前接口的 Method-Code，已显式设置的请求头不会被覆盖。
+// FCode 取入站请求所属实例的服务编码，未关联入站请求时取 engine 的服务编码。
+type PropagationTripper struct {
+	next   http.RoundTripper
+	engine *GinQQ
+}
+
+func NewPropagationTripper() *PropagationTripper {
+	return &PropagationTripper{}
+}
+
+func (p *PropagationTripper) SetNext(next http.RoundTripper) {
+	p.next = next
+}
+
+func (p *PropagationTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTripper 不应修改原始请求，克隆后再设置请求头
+	req = req.Clone(req.Context())
+
+	if engine := outboundEngine(req, p.engine); engine != nil && req.Header.Get(XFCode) == "" {
+		req.Header.Set(XFCode, engine.Config.Identity().SvcCode)
+	}
+
+	values := outboundValuesFromContext(req.Context())
+	if values == nil {
+		return p.next.RoundTrip(req)
+	}
+	if req.Header.Get(XTraceID) == "" && values.traceID != "" {
+		req.Header.Set(XTraceID, values.traceID)
+	}
+	if values.span != nil && req.Header.Get(XTraceparent) == "" {
+		req.Header.Set(XTraceparent, values.span.Traceparent())
+		if values.span.TraceState != "" {
+			req.Header.Set(XTracestate, values.span.TraceState)
+		}
+	}
+	if req.Header.Get(XTransactionID) == "" {
+		req.Header.Set(XTransactionID, uuid4()) // 每次调用生成子流水号
+	}
+	if req.Header.Get(XMethodCode) == "" && values.methodCode != "" {
+		req.Header.Set(XMethodCode, values.methodCode)
+	}
+	return p.next.RoundTrip(req)
+}
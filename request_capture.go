@@ -0,0 +1,171 @@
+package ginqq
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const defaultRequestCaptureLimit = 64 << 10
+
+// captureOptions 流水日志请求体/响应体的记录规则。
+type captureOptions struct {
+	requestLimit  int
+	responseLimit int
+	textTypes     map[string]bool // 额外按文本记录的媒体类型
+}
+
+// captureSettings 返回流水配置对应的记录规则，未配置时使用默认值。
+func captureSettings(cfg *TransactionLogConfig) *captureOptions {
+	opts := &captureOptions{requestLimit: defaultRequestCaptureLimit, responseLimit: defaultResponseCaptureLimit}
+	if cfg == nil {
+		return opts
+	}
+	if cfg.RequestCaptureLimit > 0 {
+		opts.requestLimit = cfg.RequestCaptureLimit
+	}
+	if cfg.ResponseCaptureLimit > 0 {
+		opts.responseLimit = cfg.ResponseCaptureLimit
+	}
+	if len(cfg.TextContentTypes) != 0 {
+		opts.textTypes = make(map[string]bool, len(cfg.TextContentTypes))
+		for _, t := range cfg.TextContentTypes {
+			opts.textTypes[strings.ToLower(t)] = true
+		}
+	}
+	return opts
+}
+
+func (o *captureOptions) isText(mediaType string) bool {
+	return isTextContentType(mediaType) || o.textTypes[mediaType]
+}
+
+// requestCapture 包装请求体，在业务读取请求体的同时保留前 limit 字节（边读边记录，不整体缓存），
+// multipart 和二进制内容只统计大小。
+type requestCapture struct {
+	io.ReadCloser
+
+	mediaType string
+	keep      bool // 是否保留内容
+	body      bytes.Buffer
+	limit     int
+	size      int64 // 已读取的字节数
+	eof       bool
+}
+
+func newRequestCapture(req *http.Request, opts *captureOptions) *requestCapture {
+	r := &requestCapture{ReadCloser: req.Body, limit: opts.requestLimit}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		r.mediaType, _, _ = mime.ParseMediaType(contentType)
+	}
+	r.keep = r.mediaType == "" || opts.isText(r.mediaType)
+	return r
+}
+
+func (r *requestCapture) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.record(p[:n], err)
+	return n, err
+}
+
+// record 记录读取到的内容。
+func (r *requestCapture) record(p []byte, err error) {
+	n := len(p)
+	r.size += int64(n)
+	if r.keep {
+		if remain := r.limit - r.body.Len(); remain > 0 {
+			r.body.Write(p[:min(n, remain)])
+		}
+	}
+	if err == io.EOF {
+		r.eof = true
+	}
+}
+
+// complete 请求处理结束后，业务未读取完的请求体继续读取至记录上限，用于判断是否截断。
+func (r *requestCapture) complete() {
+	if !r.keep || r.eof {
+		return
+	}
+	_, _ = io.CopyN(io.Discard, r, int64(r.limit-r.body.Len())+1)
+}
+
+func (r *requestCapture) truncated() bool {
+	return r.size > int64(r.body.Len())
+}
+
+// payload 组装请求数据：查询参数、表单数据、JSON 数据合并为一个对象，
+// 截断或无法解析的文本记录在 "_body" 中，multipart 记录字段值和文件摘要，二进制内容记录占位说明。
+func (r *requestCapture) payload(req *http.Request) map[string]interface{} {
+	payload := make(map[string]interface{})
+	mergeValues(payload, req.URL.Query())
+
+	switch {
+	case r.mediaType == "multipart/form-data":
+		if req.MultipartForm == nil {
+			payload["_body"] = fmt.Sprintf("[multipart body not parsed by handler, %d bytes]", r.contentLength(req))
+			break
+		}
+		mergeValues(payload, req.MultipartForm.Value)
+		var files []map[string]interface{}
+		for field, headers := range req.MultipartForm.File {
+			for _, fh := range headers {
+				files = append(files, map[string]interface{}{
+					"field":        field,
+					"filename":     fh.Filename,
+					"size":         fh.Size,
+					"content_type": fh.Header.Get("Content-Type"),
+				})
+			}
+		}
+		if len(files) != 0 {
+			payload["_files"] = files
+		}
+	case !r.keep:
+		payload["_body"] = binaryPlaceholder(req.Header.Get("Content-Type"), r.contentLength(req))
+	case r.body.Len() == 0:
+	case r.truncated():
+		payload["_body"] = r.body.String() + truncatedMarker(r.contentLength(req))
+	case r.mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(r.body.String())
+		if err != nil {
+			payload["_body"] = r.body.String()
+		}
+		mergeValues(payload, form)
+	default:
+		var data interface{}
+		if err := json.Unmarshal(r.body.Bytes(), &data); err != nil {
+			payload["_body"] = r.body.String()
+		} else if m, ok := data.(map[string]interface{}); ok {
+			for k, v := range m {
+				payload[k] = v
+			}
+		} else {
+			payload["_body"] = data
+		}
+	}
+	return payload
+}
+
+// contentLength 请求体大小，优先使用 Content-Length 。
+func (r *requestCapture) contentLength(req *http.Request) int64 {
+	if req.ContentLength > 0 {
+		return req.ContentLength
+	}
+	return r.size
+}
+
+func mergeValues(payload map[string]interface{}, values map[string][]string) {
+	for key, v := range values {
+		if len(v) == 1 {
+			payload[key] = v[0]
+		} else {
+			payload[key] = v
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package ginqq
+
+import (
+	"errors"
+	"net/http"
+)
+
+// xResponseResult ctx 中保存标准响应结果的 key，流水据此记录 response_code、error_code、response_remark 。
+const xResponseResult = "ginqq.response_result"
+
+// Response 部门接口标准响应格式。
+type Response struct {
+	Code string      `json:"code"` // 响应码，成功为 CodeSuccess
+	Msg  string      `json:"msg"`  // 响应信息
+	Data interface{} `json:"data"` // 响应数据
+}
+
+const (
+	CodeSuccess              = "0"
+	CodeApiStandardViolation = "40000" // 调用不符合部门 API 规范
+	CodeSignatureRejected    = "40100" // 请求签名校验失败
+	CodeSecurityRejected     = "40300" // 请求被安全过滤拦截
+	CodeCSRFRejected         = "40301" // 请求未通过 CSRF 校验
+	CodeInternalError        = "50000" // 未归类的服务端错误
+)
+
+// BizError 业务错误，通过 Context.Fail 以标准响应格式返回。
+// 建议通过 ErrorRegistry.Register 或 RegisterError 声明，响应信息按 Accept-Language 选择中文或英文。
+type BizError struct {
+	Code       string // 业务响应码，写入响应 code 及流水 response_code、error_code
+	Message    string // 返回调用方的信息，写入响应 msg
+	HTTPStatus int    // HTTP 状态码，默认 200
+	Remark     string // 内部备注，不返回调用方，写入流水 response_remark，为空时记录 Message
+
+	definition *ErrorDefinition // 注册的错误码定义，用于多语言信息
+	registered bool             // 是否为注册返回的共享变量（如 ErrInternal），使用时按定义生成副本
+}
+
+// NewBizError 创建业务错误，HTTP 状态码为 200 。
+func NewBizError(code, message string) *BizError {
+	return &BizError{Code: code, Message: message}
+}
+
+func (e *BizError) Error() string {
+	if e.Remark != "" {
+		return e.Code + ": " + e.Message + " (" + e.Remark + ")"
+	}
+	return e.Code + ": " + e.Message
+}
+
+// WithStatus 返回使用指定 HTTP 状态码的副本。
+func (e *BizError) WithStatus(status int) *BizError {
+	err := e.copy()
+	err.HTTPStatus = status
+	return &err
+}
+
+// WithRemark 返回附带内部备注的副本，如具体失败原因。
+func (e *BizError) WithRemark(remark string) *BizError {
+	err := e.copy()
+	err.Remark = remark
+	return &err
+}
+
+// copy 返回错误的副本。注册返回的共享变量按注册的定义生成，修改共享变量的字段不影响响应及其副本。
+func (e *BizError) copy() BizError {
+	if !e.registered {
+		return *e
+	}
+	return BizError{Code: e.definition.Code, Message: e.definition.MessageZh, HTTPStatus: e.definition.HTTPStatus, definition: e.definition}
+}
+
+// responseResult 标准响应结果。
+type responseResult struct {
+	code   string
+	remark string
+	failed bool
+}
+
+// Success 以标准响应格式返回成功结果。
+func (c *Context) Success(data interface{}) {
+	c.Set(xResponseResult, &responseResult{code: CodeSuccess})
+	c.JSON(http.StatusOK, Response{Code: CodeSuccess, Msg: "success", Data: data})
+}
+
+// Fail 以标准响应格式返回错误并中止后续处理函数。err 不是 *BizError 时按 ErrInternal 返回，原错误信息记录在流水备注中，
+// err 为 nil 时按 ErrInternal 返回。
+func (c *Context) Fail(err error) {
+	c.fail(err, "", nil)
+}
+
+// fail 返回错误，detail 附加在响应信息之后。
+func (c *Context) fail(err error, detail string, data interface{}) {
+	var e *BizError
+	if err != nil && !errors.As(err, &e) {
+		e = ErrInternal.WithRemark(err.Error())
+	}
+	if e == nil { // err 为 nil 或 (*BizError)(nil)
+		e = ErrInternal
+	}
+	bizErr := e.copy()
+	msg := bizErr.Message
+	if bizErr.definition != nil && msg == bizErr.definition.MessageZh {
+		msg = bizErr.definition.message(c.GetHeader("Accept-Language"))
+	}
+	if detail != "" {
+		msg += ": " + detail
+	}
+	remark := bizErr.Remark
+	if remark == "" {
+		remark = msg
+	}
+	status := bizErr.HTTPStatus
+	if status == 0 {
+		status = http.StatusOK
+	}
+	c.Set(xResponseResult, &responseResult{code: bizErr.Code, remark: remark, failed: true})
+	c.JSON(status, Response{Code: bizErr.Code, Msg: msg, Data: data})
+	c.Abort()
+}
+
+// getResponseResult 获取 Success、Fail 设置的响应结果，未通过其响应时返回 nil 。
+func (c *Context) getResponseResult() *responseResult {
+	if result, ok := c.Get(xResponseResult); ok {
+		return result.(*responseResult)
+	}
+	return nil
+}
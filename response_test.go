@@ -0,0 +1,63 @@
+package ginqq
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContextSuccessAndFail(t *testing.T) {
+	lines := make(chan []byte, 1)
+	g := newTestGinQQ(t, &Config{}, func(msg []byte) { lines <- msg })
+	g.Use(DispatchTransactionLog)
+	errBalance := NewBizError("10001", "余额不足")
+	g.GET("/ok", func(c *Context) { c.Success(H{"id": 1}) })
+	g.GET("/balance", func(c *Context) { c.Fail(errBalance.WithRemark("balance=0, amount=10")) })
+	g.GET("/missing", func(c *Context) { c.Fail(NewBizError("10404", "订单不存在").WithStatus(http.StatusNotFound)) })
+	g.GET("/internal", func(c *Context) { c.Fail(errors.New("db down")) }, func(c *Context) {
+		c.String(http.StatusOK, "unreachable") // Fail 中止后续处理函数
+	})
+	g.GET("/nil", func(c *Context) { c.Fail(nil) })
+	g.GET("/nil-biz", func(c *Context) {
+		var err *BizError
+		c.Fail(err)
+	})
+	errRegistered := NewErrorRegistry().Register(ErrorDefinition{Code: "10002", Category: ErrorCategoryClient, MessageZh: "库存不足"})
+	errRegistered.Remark, errRegistered.HTTPStatus = "leaked", http.StatusTeapot // 修改共享变量不生效
+	g.GET("/registered", func(c *Context) { c.Fail(errRegistered) })
+
+	for _, tt := range []struct {
+		path                    string
+		status                  int
+		body                    string
+		responseCode, errorCode string
+		responseRemark          string
+	}{
+		{"/ok", 200, `{"code":"0","msg":"success","data":{"id":1}}`, "0", "", ""},
+		{"/balance", 200, `{"code":"10001","msg":"余额不足","data":null}`, "10001", "10001", "balance=0, amount=10"},
+		{"/missing", 404, `{"code":"10404","msg":"订单不存在","data":null}`, "10404", "10404", "订单不存在"},
+		{"/internal", 500, `{"code":"50000","msg":"服务内部错误","data":null}`, "50000", "50000", "db down"},
+		{"/nil", 500, `{"code":"50000","msg":"服务内部错误","data":null}`, "50000", "50000", "服务内部错误"},
+		{"/nil-biz", 500, `{"code":"50000","msg":"服务内部错误","data":null}`, "50000", "50000", "服务内部错误"},
+		{"/registered", 200, `{"code":"10002","msg":"库存不足","data":null}`, "10002", "10002", "库存不足"},
+	} {
+		w := httptest.NewRecorder()
+		g.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.status || w.Body.String() != tt.body {
+			t.Errorf("%s: got %d %s, want %d %s", tt.path, w.Code, w.Body, tt.status, tt.body)
+		}
+		var log TransactionLog
+		if err := json.Unmarshal(<-lines, &log); err != nil {
+			t.Fatal(err)
+		}
+		if log.ResponseCode != tt.responseCode || log.ErrorCode != tt.errorCode || log.ResponseRemark != tt.responseRemark {
+			t.Errorf("%s: transaction log response_code=%q error_code=%q response_remark=%q", tt.path, log.ResponseCode, log.ErrorCode, log.ResponseRemark)
+		}
+	}
+
+	if err := error(errBalance.WithRemark("x")); err.Error() != "10001: 余额不足 (x)" {
+		t.Errorf("Error() = %q", err)
+	}
+}
@@ -0,0 +1,270 @@
+package ginqq
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const defaultResponseCaptureLimit = 64 << 10
+
+// responseCaptureWriter 包装 gin.ResponseWriter，在写出响应的同时保留响应体副本用于流水日志。
+type responseCaptureWriter struct {
+	gin.ResponseWriter
+	responseCapture
+}
+
+func newResponseCaptureWriter(w gin.ResponseWriter, opts *captureOptions) *responseCaptureWriter {
+	return &responseCaptureWriter{ResponseWriter: w, responseCapture: responseCapture{header: w.Header(), opts: opts}}
+}
+
+func (w *responseCaptureWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.capture(b[:n])
+	return n, err
+}
+
+func (w *responseCaptureWriter) WriteString(s string) (int, error) {
+	n, err := w.ResponseWriter.WriteString(s)
+	w.capture([]byte(s[:n]))
+	return n, err
+}
+
+// responseCapture 保留响应体前 responseLimit 字节用于流水日志，二进制内容只记录大小，
+// 入站响应和出站响应共用。
+type responseCapture struct {
+	header    http.Header
+	body      bytes.Buffer
+	opts      *captureOptions
+	size      int64  // 实际写出的字节数
+	mediaType string // 首次写入时根据 Content-Type 判断
+	binary    bool
+	sniffed   bool
+}
+
+func (w *responseCapture) capture(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	if !w.sniffed {
+		w.sniffed = true
+		w.mediaType = w.contentType(b)
+		w.binary = !w.opts.isText(w.mediaType)
+	}
+	w.size += int64(len(b))
+	if w.binary {
+		return
+	}
+	if remain := w.opts.responseLimit - w.body.Len(); remain > 0 {
+		if len(b) > remain {
+			b = b[:remain]
+		}
+		w.body.Write(b)
+	}
+}
+
+// contentType 返回响应的媒体类型，未设置 Content-Type 时根据内容推断。
+func (w *responseCapture) contentType(firstChunk []byte) string {
+	contentType := w.header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(firstChunk)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(contentType)
+	}
+	return mediaType
+}
+
+// truncated 响应体是否超过保留上限。
+func (w *responseCapture) truncated() bool {
+	return w.size > int64(w.body.Len())
+}
+
+// payload 将保留的响应体序列化为流水 response_payload：完整的 JSON 原样记录，
+// 文本记录为 JSON 字符串，超出上限时追加截断标记，二进制内容记录为占位说明。
+func (w *responseCapture) payload() string {
+	if w.size == 0 {
+		return "{}"
+	}
+	if w.binary {
+		return marshalText(binaryPlaceholder(w.header.Get("Content-Type"), w.size))
+	}
+	if !w.truncated() {
+		var compacted bytes.Buffer
+		if json.Compact(&compacted, w.body.Bytes()) == nil {
+			return compacted.String()
+		}
+	}
+	text := w.body.String()
+	if w.truncated() {
+		text += truncatedMarker(w.size)
+	}
+	return marshalText(text)
+}
+
+// marshalText 将文本序列化为 JSON 字符串，保持 XML/HTML 响应可读。
+// objectPayload 将 Context.JSON 等方法传入的对象序列化为流水 response_payload，
+// 与 responseCapture.payload 一致：不转义 HTML 字符，超出 responseLimit 时截断并追加截断标记。
+func (o *captureOptions) objectPayload(v interface{}) string {
+	serialized, err := marshalNoEscape(v)
+	if err != nil {
+		return "{}"
+	}
+	if len(serialized) <= o.responseLimit {
+		return serialized
+	}
+	return marshalText(serialized[:o.responseLimit] + truncatedMarker(int64(len(serialized))))
+}
+
+func marshalText(text string) string {
+	serialized, _ := marshalNoEscape(text)
+	return serialized
+}
+
+// data 返回响应体反序列化后的数据，用于提取 code、order_id 等字段，超出保留上限时解析已保留的部分。
+func (w *responseCapture) data() interface{} {
+	if w.binary || w.size == 0 {
+		return nil
+	}
+	return decodePayload(w.mediaType, w.body.Bytes())
+}
+
+// decodePayload 将 JSON、XML、YAML 文本反序列化为通用数据（map[string]interface{} 等），其他文本按 JSON 尝试。
+// 内容被截断时返回能解析出的部分，被截断的字段值不返回。
+func decodePayload(mediaType string, body []byte) interface{} {
+	switch {
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		return decodeXMLPrefix(body)
+	case mediaType == "application/yaml" || mediaType == "application/x-yaml" ||
+		mediaType == "text/yaml" || mediaType == "text/x-yaml":
+		return decodeYAMLPrefix(body)
+	}
+	var data interface{}
+	if json.Unmarshal(body, &data) == nil {
+		return data
+	}
+	data, _ = decodeJSONValue(json.NewDecoder(bytes.NewReader(body)))
+	return data
+}
+
+// decodeJSONValue 逐个 token 解析 JSON 值，遇到错误（如内容截断）时返回已解析的部分及 false 。
+func decodeJSONValue(dec *json.Decoder) (interface{}, bool) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, false
+	}
+	switch token {
+	case json.Delim('{'):
+		m := make(map[string]interface{})
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return m, false
+			}
+			value, ok := decodeJSONValue(dec)
+			if value != nil || ok {
+				m[fmt.Sprint(key)] = value
+			}
+			if !ok {
+				return m, false
+			}
+		}
+		_, err := dec.Token()
+		return m, err == nil
+	case json.Delim('['):
+		var items []interface{}
+		for dec.More() {
+			value, ok := decodeJSONValue(dec)
+			if value != nil || ok {
+				items = append(items, value)
+			}
+			if !ok {
+				return items, false
+			}
+		}
+		_, err := dec.Token()
+		return items, err == nil
+	}
+	return token, true
+}
+
+// decodeXMLPrefix 将 XML 元素解析为嵌套 map，只含文本的元素记录为字符串，同名元素只保留第一个。
+func decodeXMLPrefix(body []byte) interface{} {
+	root := make(map[string]interface{})
+	decodeXMLChildren(xml.NewDecoder(bytes.NewReader(body)), root)
+	if len(root) == 0 {
+		return nil
+	}
+	return root
+}
+
+// decodeXMLChildren 解析当前元素的子元素到 parent，返回元素文本，元素未完整解析时返回 false 。
+func decodeXMLChildren(dec *xml.Decoder, parent map[string]interface{}) (string, bool) {
+	var text strings.Builder
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return text.String(), false
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			child := make(map[string]interface{})
+			childText, ok := decodeXMLChildren(dec, child)
+			if _, exists := parent[t.Name.Local]; !exists {
+				if len(child) != 0 {
+					parent[t.Name.Local] = child
+				} else if ok {
+					parent[t.Name.Local] = strings.TrimSpace(childText)
+				}
+			}
+			if !ok {
+				return text.String(), false
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			return text.String(), true
+		}
+	}
+}
+
+// decodeYAMLPrefix 解析 YAML，内容截断无法解析时丢弃最后一个不完整的行后重试。
+func decodeYAMLPrefix(body []byte) interface{} {
+	var data interface{}
+	if yaml.Unmarshal(body, &data) == nil {
+		return data
+	}
+	if i := bytes.LastIndexByte(body, '\n'); i > 0 && yaml.Unmarshal(body[:i], &data) == nil {
+		return data
+	}
+	return nil
+}
+
+func truncatedMarker(size int64) string {
+	return fmt.Sprintf("...[truncated, %d bytes total]", size)
+}
+
+func binaryPlaceholder(contentType string, size int64) string {
+	return fmt.Sprintf("[binary content omitted, content-type: %s, %d bytes]", contentType, size)
+}
+
+// isTextContentType 判断媒体类型是否为可读文本。
+func isTextContentType(mediaType string) bool {
+	if strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/x-yaml", "application/yaml",
+		"application/javascript", "application/x-www-form-urlencoded":
+		return true
+	}
+	return false
+}
@@ -23,6 +23,18 @@ func (g *RouterGroup) PUT(relativePath string, handlers ...func(*Context)) {
 	g.handle("PUT", relativePath, handlers)
 }
 
+// Use 为路由组添加中间件，如 CSRF 。
+func (g *RouterGroup) Use(handlers ...func(*Context)) {
+	g.RouterGroup.Use(convertToGinHandlers(handlers)...)
+}
+
+// Group 返回子路由组。
+func (g *RouterGroup) Group(relativePath string, handlers ...func(*Context)) *RouterGroup {
+	return &RouterGroup{
+		RouterGroup: g.RouterGroup.Group(relativePath, convertToGinHandlers(handlers)...),
+	}
+}
+
 func (g *RouterGroup) handle(method, relativePath string, handlers []func(*Context)) {
 	g.RouterGroup.Handle(method, relativePath, convertToGinHandlers(handlers)...)
 }
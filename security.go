@@ -0,0 +1,314 @@
+package ginqq
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// xSecurityEvents ctx 中保存安全规则命中记录的 key，流水据此记录 security_event 。
+const xSecurityEvents = "ginqq.security_events"
+
+const defaultSecurityBodyLimit = 1 << 20
+
+// 安全规则分类。
+const (
+	SecurityCategoryXSS  = "xss"
+	SecurityCategorySQLi = "sqli"
+)
+
+var ErrSecurityRejected = RegisterError(ErrorDefinition{
+	Code: CodeSecurityRejected, HTTPStatus: http.StatusForbidden, Category: ErrorCategoryClient,
+	MessageZh: "请求包含不安全的内容", MessageEn: "request contains unsafe content",
+})
+
+// SecurityRule 安全过滤规则，请求参数、请求体中的字段名及字段值、指定请求头匹配 Pattern 时命中。
+type SecurityRule struct {
+	ID       string // 规则标识，如 xss-script-tag，用于路由放行及流水记录
+	Category string // 规则分类，如 xss、sqli
+	Pattern  string // 正则表达式，匹配时忽略大小写
+
+	pattern *regexp.Regexp
+}
+
+// builtinSecurityRules 内置 XSS、SQL 注入特征。
+var builtinSecurityRules = []SecurityRule{
+	{ID: "xss-script-tag", Category: SecurityCategoryXSS, Pattern: `<\s*/?\s*script\b`},
+	{ID: "xss-event-handler", Category: SecurityCategoryXSS, Pattern: `<[^>]*\bon[a-z]+\s*=`},
+	{ID: "xss-javascript-uri", Category: SecurityCategoryXSS, Pattern: `\b(java|vb)script\s*:`},
+	{ID: "xss-embedded-tag", Category: SecurityCategoryXSS, Pattern: `<\s*(iframe|frame|object|embed|applet|base|meta)\b`},
+	{ID: "sqli-union-select", Category: SecurityCategorySQLi, Pattern: `\bunion\b(\s+all)?\s+select\b`},
+	{ID: "sqli-tautology", Category: SecurityCategorySQLi, Pattern: `['"]\s*(or|and)\s+['"]?\w+['"]?\s*(=|like)\s*['"]?\w+`},
+	{ID: "sqli-comment", Category: SecurityCategorySQLi, Pattern: `['"]\s*\)?\s*(--|#)\s*$|['"]\s*/\*`},
+	{ID: "sqli-stacked-query", Category: SecurityCategorySQLi,
+		Pattern: `;\s*(drop\s+(table|database)|delete\s+from|update\s+\w+\s+set|insert\s+into|alter\s+table|truncate\s+table|create\s+(table|database)|exec(ute)?\s+\w+)\b`},
+	{ID: "sqli-time-based", Category: SecurityCategorySQLi,
+		Pattern: `\b(sleep|pg_sleep)\s*\(\s*\d+(\.\d+)?\s*\)|\bbenchmark\s*\(\s*\d+\s*,|\bwaitfor\s+delay\b`},
+}
+
+// securityRuleBodyTooLarge 请求体超出 SecurityConfig.BodyLimit 无法检查时记录的规则，可在 AllowList 中按路由放行（如上传接口）。
+const securityRuleBodyTooLarge = "body-too-large"
+
+// SecurityEvent 安全规则命中记录。
+type SecurityEvent struct {
+	RuleID   string `json:"rule_id"`
+	Category string `json:"category"`
+	Location string `json:"location"` // 命中位置，如 query.name、body.user.name、form.comment、header.Referer
+	Match    string `json:"match"`    // 命中的内容，最多 64 个字符，经脱敏
+	Action   string `json:"action"`   // blocked 或 reported
+}
+
+// securityFilter 安全过滤规则引擎。
+type securityFilter struct {
+	cfg   *SecurityConfig
+	rules []SecurityRule
+}
+
+// compileSecurityRules 编译内置规则及自定义规则。
+func compileSecurityRules(cfg *SecurityConfig) ([]SecurityRule, error) {
+	var rules []SecurityRule
+	if !cfg.DisableBuiltinRules {
+		rules = append(rules, builtinSecurityRules...)
+	}
+	rules = append(rules, cfg.Rules...)
+
+	var errs []error
+	ids := make(map[string]bool, len(rules))
+	for i := range rules {
+		rule := &rules[i]
+		if rule.ID == "" || ids[rule.ID] {
+			errs = append(errs, fmt.Errorf("SecurityConfig.Rules: rule ID %q is empty or duplicated", rule.ID))
+		}
+		ids[rule.ID] = true
+		pattern, err := regexp.Compile(`(?i)` + rule.Pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("SecurityConfig.Rules: rule %q: %w", rule.ID, err))
+		}
+		rule.pattern = pattern
+	}
+	return rules, errors.Join(errs...)
+}
+
+func newSecurityFilter(cfg *SecurityConfig) *securityFilter {
+	return &securityFilter{cfg: cfg, rules: cfg.rules}
+}
+
+func (f *securityFilter) handle(c *Context) {
+	allowed := f.cfg.AllowList[c.FullPath()]
+	for _, id := range allowed {
+		if id == "*" {
+			c.Next()
+			return
+		}
+	}
+	events := f.inspect(c, allowed)
+	if len(events) == 0 {
+		c.Next()
+		return
+	}
+
+	action := "reported"
+	if f.cfg.Enforce {
+		action = "blocked"
+	}
+	masker := c.Engine().masker
+	for i := range events {
+		events[i].Action = action
+		if masker != nil {
+			events[i].Match = masker.maskString(events[i].Match)
+		}
+	}
+	c.Set(xSecurityEvents, events)
+	c.Logger().WithField("security_event", events).Warn("request matched security rules")
+	if !f.cfg.Enforce {
+		c.Next()
+		return
+	}
+	c.Fail(ErrSecurityRejected) // 不向调用方透露命中的规则
+}
+
+// inspect 检查查询参数、表单、JSON 请求体及指定请求头，返回命中记录。
+func (f *securityFilter) inspect(c *Context, allowed []string) []SecurityEvent {
+	var events []SecurityEvent
+	check := func(location, value string) {
+		for _, candidate := range normalizeSecurityValue(value) {
+			for _, rule := range f.rules {
+				if securityRuleAllowed(rule, allowed) {
+					continue
+				}
+				if match := rule.pattern.FindString(candidate); match != "" {
+					if len(match) > 64 {
+						match = match[:64]
+					}
+					events = append(events, SecurityEvent{RuleID: rule.ID, Category: rule.Category, Location: location, Match: match})
+					return // 同一位置只记录首个命中的规则
+				}
+			}
+		}
+	}
+
+	checkValues("query", c.Request.URL.Query(), check)
+	for _, name := range f.cfg.Headers {
+		for _, value := range c.Request.Header.Values(name) {
+			check("header."+http.CanonicalHeaderKey(name), value)
+		}
+	}
+
+	body, err := f.readBody(c)
+	if err == errSecurityBodyTooLarge {
+		if !securityRuleAllowed(SecurityRule{ID: securityRuleBodyTooLarge, Category: "limit"}, allowed) {
+			events = append(events, SecurityEvent{RuleID: securityRuleBodyTooLarge, Category: "limit", Location: "body"})
+		}
+		return events
+	}
+	if err != nil || len(body) == 0 {
+		return events
+	}
+	mediaType, params, _ := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "multipart/form-data":
+		checkMultipart(body, params["boundary"], check)
+	case mediaType == "application/x-www-form-urlencoded":
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			checkValues("form", form, check)
+		}
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var data interface{}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if decoder.Decode(&data) == nil {
+			walkJSON("body", data, check)
+		} else {
+			check("body", string(body)) // 无法解析时按原文检查
+		}
+	}
+	return events
+}
+
+var errSecurityBodyTooLarge = errors.New("request body exceeds SecurityConfig.BodyLimit")
+
+// readBody 读取请求体并保留给后续处理函数，已知长度超出上限时不读取，未知长度时最多读取上限字节检查，
+// 超出上限时返回 errSecurityBodyTooLarge 。
+func (f *securityFilter) readBody(c *Context) ([]byte, error) {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return nil, nil
+	}
+	if length := c.Request.ContentLength; length >= 0 {
+		if length > int64(f.cfg.BodyLimit) {
+			return nil, errSecurityBodyTooLarge
+		}
+		return c.GetRawDataReusable()
+	}
+	original := c.Request.Body
+	head, err := io.ReadAll(io.LimitReader(original, int64(f.cfg.BodyLimit)+1))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), original), original}
+	if err == nil && len(head) > f.cfg.BodyLimit {
+		err = errSecurityBodyTooLarge
+	}
+	return head, err
+}
+
+// checkMultipart 检查 multipart 表单的字段值及文件名，文件内容不检查。
+func checkMultipart(body []byte, boundary string, check func(location, value string)) {
+	if boundary == "" {
+		return
+	}
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return
+		}
+		name := part.FormName()
+		check("form."+name, name)
+		if filename := part.FileName(); filename != "" {
+			check("form."+name+".filename", filename)
+			continue
+		}
+		if value, err := io.ReadAll(part); err == nil {
+			check("form."+name, string(value))
+		}
+	}
+}
+
+func securityRuleAllowed(rule SecurityRule, allowed []string) bool {
+	for _, id := range allowed {
+		if id == rule.ID || id == rule.Category {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeSecurityValue 返回原值及 URL、HTML 解码后的值，识别编码绕过。
+func normalizeSecurityValue(value string) []string {
+	values := []string{value}
+	if strings.Contains(value, "%") {
+		if decoded, err := url.QueryUnescape(value); err == nil && decoded != value {
+			values = append(values, decoded)
+		}
+	}
+	if strings.Contains(value, "&") {
+		if decoded := html.UnescapeString(value); decoded != value {
+			values = append(values, decoded)
+		}
+	}
+	return values
+}
+
+func checkValues(location string, values url.Values, check func(location, value string)) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		check(location+"."+key, key)
+		for _, value := range values[key] {
+			check(location+"."+key, value)
+		}
+	}
+}
+
+// walkJSON 检查 JSON 中的所有字段名及字符串值。
+func walkJSON(location string, data interface{}, check func(location, value string)) {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			check(location+"."+key, key)
+			walkJSON(location+"."+key, v[key], check)
+		}
+	case []interface{}:
+		for i, item := range v {
+			walkJSON(fmt.Sprintf("%s[%d]", location, i), item, check)
+		}
+	case string:
+		check(location, v)
+	}
+}
+
+// getSecurityEvents 获取安全规则命中记录。
+func (c *Context) getSecurityEvents() []SecurityEvent {
+	if events, ok := c.Get(xSecurityEvents); ok {
+		return events.([]SecurityEvent)
+	}
+	return nil
+}
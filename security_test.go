@@ -0,0 +1,118 @@
+package ginqq
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSecurityMiddleware(t *testing.T) {
+	lines := make(chan []byte, 1)
+	g := newTestGinQQ(t, &Config{SecurityConfig: &SecurityConfig{
+		Enforce:   true,
+		BodyLimit: 256,
+		AllowList: map[string][]string{"/articles/:id": {SecurityCategoryXSS}, "/uploads": {"body-too-large"}},
+	}}, func(msg []byte) { lines <- msg })
+	g.Use(DispatchTransactionLog, SecurityMiddleware())
+	echo := func(c *Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	}
+	g.GET("/search", echo)
+	g.POST("/comments", echo)
+	g.POST("/articles/:id", echo)
+	g.POST("/uploads", echo)
+	multipartBody := "--b\r\nContent-Disposition: form-data; name=\"title\"\r\n\r\nx' union select password from users\r\n--b--\r\n"
+	large := `{"text":"` + strings.Repeat("a", 300) + `"}`
+
+	for _, tt := range []struct {
+		name, method, target, contentType, body string
+		header                                  map[string]string
+		chunked                                 bool
+		rule, location                          string
+	}{
+		{name: "clean", method: "GET", target: "/search?q=golang+tips"},
+		{name: "plain text", method: "POST", target: "/comments", contentType: "application/json",
+			body: `{"a":"ok; update later","b":"it's #1","c":"sleep (8h)","d":"\"#1\" pick"}`},
+		{name: "query", method: "GET", target: "/search?q=1%27+or+%271%27%3D%271", rule: "sqli-tautology", location: "query.q"},
+		{name: "double encoded", method: "GET", target: "/search?q=%253Cscript%253Ealert(1)", rule: "xss-script-tag", location: "query.q"},
+		{name: "json", method: "POST", target: "/comments", contentType: "application/json",
+			body: `{"post":{"tags":["go","<img src=x onerror=alert(1)>"]}}`, rule: "xss-event-handler", location: "body.post.tags[1]"},
+		{name: "form", method: "POST", target: "/comments", contentType: "application/x-www-form-urlencoded",
+			body: "content=hi%3B+DROP+TABLE+users", rule: "sqli-stacked-query", location: "form.content"},
+		{name: "chunked", method: "POST", target: "/comments", contentType: "application/json", chunked: true,
+			body: `{"q":"1 union select password from users"}`, rule: "sqli-union-select", location: "body.q"},
+		{name: "multipart", method: "POST", target: "/comments", contentType: "multipart/form-data; boundary=b",
+			body: multipartBody, rule: "sqli-union-select", location: "form.title"},
+		{name: "oversized", method: "POST", target: "/comments", contentType: "application/json", body: large,
+			rule: "body-too-large", location: "body"},
+		{name: "oversized chunked", method: "POST", target: "/comments", contentType: "application/json", body: large, chunked: true,
+			rule: "body-too-large", location: "body"},
+		{name: "oversized allowed", method: "POST", target: "/uploads", contentType: "application/json", body: large},
+		{name: "header", method: "GET", target: "/search", header: map[string]string{"Referer": "javascript:alert(1)"},
+			rule: "xss-javascript-uri", location: "header.Referer"},
+		{name: "allowed category", method: "POST", target: "/articles/1", contentType: "application/json",
+			body: `{"html":"<script src=editor.js></script>"}`},
+		{name: "allowed route still checks sqli", method: "POST", target: "/articles/1", contentType: "application/json",
+			body: `{"id":"1; delete from articles"}`, rule: "sqli-stacked-query", location: "body.id"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			req.Header.Set("Content-Type", tt.contentType)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			g.ServeHTTP(w, req)
+			var log TransactionLog
+			if err := json.Unmarshal(<-lines, &log); err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.rule == "" {
+				if w.Code != http.StatusOK || w.Body.String() != tt.body || log.SecurityEvent != "" {
+					t.Errorf("got %d %q, security_event %q", w.Code, w.Body, log.SecurityEvent)
+				}
+				return
+			}
+			if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), `"code":"40300"`) {
+				t.Fatalf("got %d %s, want 403", w.Code, w.Body)
+			}
+			var events []SecurityEvent
+			if err := json.Unmarshal([]byte(log.SecurityEvent), &events); err != nil {
+				t.Fatalf("invalid security_event %q: %v", log.SecurityEvent, err)
+			}
+			if len(events) != 1 || events[0].RuleID != tt.rule || events[0].Location != tt.location || events[0].Action != "blocked" {
+				t.Errorf("unexpected security_event: %s", log.SecurityEvent)
+			}
+		})
+	}
+
+	// 默认只记录不拦截，命中内容脱敏
+	reportOnly := newTestGinQQ(t, &Config{}, func(msg []byte) { lines <- msg })
+	reportOnly.Use(DispatchTransactionLog, SecurityMiddleware())
+	reportOnly.GET("/search", echo)
+	w := httptest.NewRecorder()
+	reportOnly.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search?q=%27+or+%2713800138000%27%3D%2713800138000", nil))
+	var log TransactionLog
+	if err := json.Unmarshal(<-lines, &log); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || !strings.Contains(log.SecurityEvent, `"action":"reported"`) ||
+		strings.Contains(log.SecurityEvent, "13800138000") {
+		t.Errorf("report-only: got %d, security_event %s", w.Code, log.SecurityEvent)
+	}
+
+	_, err := newGinQQ(&Config{SvcCode: "A186010101", AppName: "ginqq_test", DisableProgramLog: true,
+		SecurityConfig: &SecurityConfig{Rules: []SecurityRule{{ID: "xss-script-tag", Pattern: "("}}}})
+	if err == nil || !strings.Contains(err.Error(), `rule ID "xss-script-tag" is empty or duplicated`) ||
+		!strings.Contains(err.Error(), "missing closing )") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,241 @@
+package ginqq
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 请求签名相关请求头。
+const (
+	XSignature          = "X-Signature"           // base64(HMAC-SHA256(密钥, 签名串))
+	XSignatureTimestamp = "X-Signature-Timestamp" // 签名时间，Unix 秒
+	XSignatureNonce     = "X-Signature-Nonce"     // 单次有效的随机串
+
+	xVerifiedFCode = "ginqq.verified_fcode" // ctx 中保存已验证调用方服务编码的 key
+
+	defaultMaxSignedBodySize = 10 << 20
+)
+
+var ErrSignatureRejected = RegisterError(ErrorDefinition{
+	Code: CodeSignatureRejected, HTTPStatus: http.StatusUnauthorized, Category: ErrorCategoryClient,
+	MessageZh: "请求签名校验失败", MessageEn: "request signature verification failed",
+})
+
+// NonceStore 签名随机串存储，用于识别重放请求，多副本部署时可使用 Redis 等共享存储实现。
+type NonceStore interface {
+	// Use 记录随机串，ttl 内已记录过时返回 false 。
+	Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// MemoryNonceStore 进程内随机串存储，只能识别打到同一副本的重放请求。
+type MemoryNonceStore struct {
+	mu        sync.Mutex
+	nonces    map[string]time.Time // 随机串 -> 过期时间
+	lastSweep time.Time
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[string]time.Time)}
+}
+
+func (s *MemoryNonceStore) Use(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for n, expiresAt := range s.nonces {
+			if now.After(expiresAt) {
+				delete(s.nonces, n)
+			}
+		}
+		s.lastSweep = now
+	}
+	if expiresAt, ok := s.nonces[nonce]; ok && now.Before(expiresAt) {
+		return false, nil
+	}
+	s.nonces[nonce] = now.Add(ttl)
+	return true, nil
+}
+
+// readSignedBody 读取参与签名的请求体，超出 limit 时返回 errSignedBodyTooLarge，不继续读取。
+func readSignedBody(body io.Reader, contentLength, limit int64) ([]byte, error) {
+	if contentLength > limit {
+		return nil, errSignedBodyTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errSignedBodyTooLarge
+	}
+	return data, nil
+}
+
+var errSignedBodyTooLarge = errors.New("request body exceeds MaxSignedBodySize")
+
+// requestSignature 计算签名：签名串为请求方法、路径及查询参数、FCode、请求体 SHA-256（十六进制）、时间戳、随机串，以换行分隔。
+func requestSignature(key []byte, method, uri, fcode string, body []byte, timestamp, nonce string) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strings.Join([]string{method, uri, fcode, hex.EncodeToString(bodyHash[:]), timestamp, nonce}, "\n")))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SigningTripper 出站请求签名中间件，使用 SigningConfig.Keys 中请求 FCode 对应的密钥签名，
+// 需位于 PropagationTripper 之后，实例未配置签名或没有对应密钥时不签名。
+type SigningTripper struct {
+	next   http.RoundTripper
+	engine *GinQQ
+}
+
+func NewSigningTripper() *SigningTripper {
+	return &SigningTripper{}
+}
+
+func (s *SigningTripper) SetNext(next http.RoundTripper) {
+	s.next = next
+}
+
+func (s *SigningTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	engine := outboundEngine(req, s.engine)
+	if engine == nil || engine.Config.SigningConfig == nil || engine.Config.SigningConfig.DisableSign {
+		return s.next.RoundTrip(req)
+	}
+	cfg := engine.Config.SigningConfig
+	fcode := strings.ToUpper(req.Header.Get(XFCode))
+	key, ok := cfg.keys[fcode]
+	if !ok {
+		return s.next.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = readSignedBody(req.Body, req.ContentLength, cfg.MaxSignedBodySize)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("signing: %w", err)
+		}
+	}
+	req = req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	}
+	timestamp, nonce := strconv.FormatInt(time.Now().Unix(), 10), uuid4()
+	req.Header.Set(XSignatureTimestamp, timestamp)
+	req.Header.Set(XSignatureNonce, nonce)
+	req.Header.Set(XSignature, requestSignature([]byte(key), req.Method, req.URL.RequestURI(), fcode, body, timestamp, nonce))
+	return s.next.RoundTrip(req)
+}
+
+// signatureVerifier 服务端签名校验。
+type signatureVerifier struct {
+	cfg       *SigningConfig
+	skipPaths map[string]bool
+}
+
+func newSignatureVerifier(cfg *SigningConfig) *signatureVerifier {
+	v := &signatureVerifier{cfg: cfg, skipPaths: make(map[string]bool, len(cfg.SkipPaths))}
+	for _, path := range cfg.SkipPaths {
+		v.skipPaths[path] = true
+	}
+	return v
+}
+
+func (v *signatureVerifier) handle(c *Context) {
+	if v.skipPaths[c.Request.URL.Path] || c.GetHeader(XSignature) == "" && v.cfg.AllowUnsigned {
+		c.Next()
+		return
+	}
+	fcode, reason := v.verify(c)
+	if reason != "" {
+		c.Logger().WithField("signature_reason", reason).Warn("request rejected by signature verification")
+		err := ErrSignatureRejected.WithRemark("signature: " + reason)
+		if reason == errSignedBodyTooLarge.Error() {
+			err = err.WithStatus(http.StatusRequestEntityTooLarge)
+		}
+		c.Fail(err)
+		return
+	}
+	c.Set(xVerifiedFCode, fcode)
+	c.Next()
+}
+
+// verify 校验签名、时间偏差及随机串，返回调用方服务编码，失败时返回原因。
+func (v *signatureVerifier) verify(c *Context) (string, string) {
+	signature := c.GetHeader(XSignature)
+	if signature == "" {
+		return "", "signature missing"
+	}
+	fcode := c.GetFCode()
+	key, ok := v.cfg.keys[fcode]
+	if !ok {
+		return "", "no key for FCode " + strconv.Quote(fcode)
+	}
+	timestamp, nonce := c.GetHeader(XSignatureTimestamp), c.GetHeader(XSignatureNonce)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || nonce == "" {
+		return "", "timestamp or nonce missing"
+	}
+	if skew := time.Since(time.Unix(unix, 0)); skew > v.cfg.MaxClockSkew || skew < -v.cfg.MaxClockSkew {
+		return "", "timestamp outside allowed clock skew"
+	}
+	body, reason := v.readBody(c)
+	if reason != "" {
+		return "", reason
+	}
+	expected := requestSignature([]byte(key), c.Request.Method, c.Request.URL.RequestURI(), fcode, body, timestamp, nonce)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return "", "signature mismatch"
+	}
+	// 签名有效后再记录随机串，避免伪造请求占用存储
+	fresh, err := v.cfg.NonceStore.Use(c.Request.Context(), fcode+":"+nonce, 2*v.cfg.MaxClockSkew)
+	if err != nil {
+		return "", "nonce store: " + err.Error()
+	}
+	if !fresh {
+		return "", "nonce already used"
+	}
+	return fcode, ""
+}
+
+// readBody 读取不超过 MaxSignedBodySize 的请求体用于计算摘要，读取后还原供后续处理使用。
+func (v *signatureVerifier) readBody(c *Context) ([]byte, string) {
+	req := c.Request
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, ""
+	}
+	body, err := readSignedBody(req.Body, req.ContentLength, v.cfg.MaxSignedBodySize)
+	if err != nil {
+		if err == errSignedBodyTooLarge {
+			return nil, err.Error()
+		}
+		return nil, "read body: " + err.Error()
+	}
+	// 保留原请求体的 Close（如流水记录包装），只替换读取来源
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{bytes.NewReader(body), req.Body}
+	return body, ""
+}
+
+// VerifiedFCode 返回通过签名校验的调用方服务编码，未签名或未开启签名校验时返回空。
+// 与 GetFCode 不同，该值不能由调用方伪造。
+func (c *Context) VerifiedFCode() string {
+	return c.GetString(xVerifiedFCode)
+}
@@ -0,0 +1,172 @@
+package ginqq
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestSigning(t *testing.T) {
+	lines := make(chan []byte, 1)
+	server := newTestGinQQ(t, &Config{SigningConfig: &SigningConfig{Keys: map[string]string{"B186010101": "secret-b"}}},
+		func(msg []byte) { lines <- msg })
+	server.Use(DispatchTransactionLog, SignatureVerifyMiddleware())
+	var lastHeader http.Header
+	server.POST("/orders", func(c *Context) {
+		lastHeader = c.Request.Header.Clone()
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, c.VerifiedFCode()+" "+string(body))
+	})
+	ts := httptest.NewServer(server.Engine)
+	defer ts.Close()
+
+	caller := newTestGinQQ(t, &Config{SvcCode: "B186010101", AppName: "caller",
+		SigningConfig: &SigningConfig{Keys: map[string]string{"B186010101": "secret-b"}}}, func([]byte) {})
+	client := &http.Client{Transport: caller.transport}
+	resp, err := client.Post(ts.URL+"/orders?id=1", "application/json", strings.NewReader(`{"amount":10}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	<-lines
+	if resp.StatusCode != http.StatusOK || string(body) != `B186010101 {"amount":10}` {
+		t.Fatalf("signed request: %d %s", resp.StatusCode, body)
+	}
+
+	send := func(header http.Header, target, body string) TransactionLog {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+target, strings.NewReader(body))
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		var log TransactionLog
+		if err := json.Unmarshal(<-lines, &log); err != nil {
+			t.Fatal(err)
+		}
+		return log
+	}
+	stale := lastHeader.Clone()
+	stale.Set(XSignatureTimestamp, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	unknown := lastHeader.Clone()
+	unknown.Set(XFCode, "C186010101")
+	unsigned := lastHeader.Clone()
+	unsigned.Del(XSignature)
+
+	for _, tt := range []struct {
+		name   string
+		header http.Header
+		target string
+		body   string
+		remark string
+	}{
+		{"replay", lastHeader, "/orders?id=1", `{"amount":10}`, "signature: nonce already used"},
+		{"tampered body", lastHeader, "/orders?id=1", `{"amount":1000}`, "signature: signature mismatch"},
+		{"tampered query", lastHeader, "/orders?id=2", `{"amount":10}`, "signature: signature mismatch"},
+		{"stale", stale, "/orders?id=1", `{"amount":10}`, "signature: timestamp outside allowed clock skew"},
+		{"unknown caller", unknown, "/orders?id=1", `{"amount":10}`, `signature: no key for FCode "C186010101"`},
+		{"unsigned", unsigned, "/orders?id=1", `{"amount":10}`, "signature: signature missing"},
+	} {
+		if log := send(tt.header, tt.target, tt.body); log.HTTPStatusCode != "401" || log.ResponseRemark != tt.remark {
+			t.Errorf("%s: status %s, response_remark %q, want %q", tt.name, log.HTTPStatusCode, log.ResponseRemark, tt.remark)
+		}
+	}
+
+	server.Config.SigningConfig.AllowUnsigned = true
+	if log := send(unsigned, "/orders", `{}`); log.HTTPStatusCode != "200" {
+		t.Errorf("AllowUnsigned: status %s", log.HTTPStatusCode)
+	}
+}
+
+func TestRequestSigningOptions(t *testing.T) {
+	keys := map[string]string{"B186010101": "secret-b"}
+	lines := make(chan []byte, 1)
+	server := newTestGinQQ(t, &Config{SigningConfig: &SigningConfig{Keys: keys, SkipPaths: []string{"/health"}, MaxSignedBodySize: 16}},
+		func(msg []byte) { lines <- msg })
+	server.Use(DispatchTransactionLog, SignatureVerifyMiddleware())
+	server.GET("/health", func(c *Context) { c.Status(http.StatusOK) })
+	server.POST("/orders", func(c *Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, c.VerifiedFCode()+" "+string(body))
+	})
+	ts := httptest.NewServer(server.Engine)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	<-lines
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("skip path: status %d", resp.StatusCode)
+	}
+
+	// 服务编码大小写不敏感
+	caller := newTestGinQQ(t, &Config{SvcCode: "B186010101", AppName: "caller",
+		SigningConfig: &SigningConfig{Keys: map[string]string{"b186010101": "secret-b"}, MaxSignedBodySize: 16}}, func([]byte) {})
+	client := &http.Client{Transport: caller.transport}
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/orders", strings.NewReader(`{"a":1}`))
+	req.Header.Set(XFCode, "b186010101")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	<-lines
+	if resp.StatusCode != http.StatusOK || string(body) != `B186010101 {"a":1}` {
+		t.Errorf("lower-case FCode: %d %s", resp.StatusCode, body)
+	}
+
+	if _, err := client.Post(ts.URL+"/orders", "application/json", strings.NewReader(`{"amount":1000000}`)); err == nil ||
+		!strings.Contains(err.Error(), "MaxSignedBodySize") {
+		t.Errorf("oversized outbound body: err %v", err)
+	}
+
+	// 入站请求体超出上限时不读取完整请求体，以 413 拒绝
+	req, _ = http.NewRequest(http.MethodPost, ts.URL+"/orders", strings.NewReader(strings.Repeat("x", 1024)))
+	req.Header.Set(XFCode, "B186010101")
+	req.Header.Set(XSignature, "forged")
+	req.Header.Set(XSignatureTimestamp, strconv.FormatInt(time.Now().Unix(), 10))
+	req.Header.Set(XSignatureNonce, "n1")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	<-lines
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized inbound body: status %d", resp.StatusCode)
+	}
+
+	signOnly := newTestGinQQ(t, &Config{SigningConfig: &SigningConfig{Keys: keys, DisableVerify: true}}, func([]byte) {})
+	if signOnly.signatureVerifier != nil {
+		t.Error("DisableVerify: verifier created")
+	}
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	store := NewMemoryNonceStore()
+	ctx := context.Background()
+	if ok, _ := store.Use(ctx, "n1", 10*time.Millisecond); !ok {
+		t.Fatal("first use rejected")
+	}
+	if ok, _ := store.Use(ctx, "n1", 10*time.Millisecond); ok {
+		t.Error("replay accepted")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if ok, _ := store.Use(ctx, "n1", 10*time.Millisecond); !ok {
+		t.Error("expired nonce rejected")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package ginqq
+
+import "net"
+
+// connClosed 非 unix 平台不探测连接状态，依赖写入失败后重连。
+func connClosed(net.Conn) bool {
+	return false
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package ginqq
+
+import (
+	"net"
+	"syscall"
+)
+
+// connClosed 以非阻塞方式窥探连接（不消费数据），读到 EOF 或错误说明对端已关闭连接。
+func connClosed(conn net.Conn) bool {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return false
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return true
+	}
+	closed := false
+	err = rc.Read(func(fd uintptr) bool {
+		var buf [1]byte
+		n, _, err := syscall.Recvfrom(int(fd), buf[:], syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		closed = n == 0 && err == nil || err != nil && err != syscall.EAGAIN && err != syscall.EWOULDBLOCK && err != syscall.EINTR
+		return true
+	})
+	return closed || err != nil
+}
@@ -0,0 +1,461 @@
+package ginqq
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultCertReloadInterval = time.Minute
+
+// certStore 证书文件，按间隔检查文件修改时间，变化时重新加载，加载失败时继续使用原证书。
+type certStore struct {
+	caFiles           []string
+	systemRoots       bool // CA 证书是否追加到系统根证书，仅用于验证服务端证书
+	certFile, keyFile string
+	interval          time.Duration
+
+	mu        sync.Mutex
+	logger    *logrus.Logger // 记录重新加载失败，默认 logrus 标准 logger，实例创建后使用实例程序日志
+	pool      *x509.CertPool // CA 证书，未配置时为 nil
+	cert      *tls.Certificate
+	checkedAt time.Time
+	modTimes  map[string]time.Time
+}
+
+func newCertStore(caFiles []string, systemRoots bool, certFile, keyFile string, interval time.Duration) (*certStore, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("CertFile and KeyFile must be set together")
+	}
+	if interval <= 0 {
+		interval = defaultCertReloadInterval
+	}
+	s := &certStore{caFiles: caFiles, systemRoots: systemRoots, certFile: certFile, keyFile: keyFile, interval: interval, logger: logrus.StandardLogger()}
+	s.modTimes = s.stat()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.checkedAt = time.Now()
+	return s, nil
+}
+
+func (s *certStore) files() []string {
+	files := append([]string(nil), s.caFiles...)
+	if s.certFile != "" {
+		files = append(files, s.certFile, s.keyFile)
+	}
+	return files
+}
+
+func (s *certStore) stat() map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	for _, file := range s.files() {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// load 加载证书文件，调用方需持有锁或在初始化时调用。
+func (s *certStore) load() error {
+	var pool *x509.CertPool
+	if len(s.caFiles) > 0 {
+		// 验证服务端证书时 CA 证书追加到系统根证书，系统根证书不可用时只使用配置的 CA；
+		// 验证客户端证书时只信任配置的 CA，否则任意公共 CA 签发的证书都能通过 mTLS 验证
+		pool = x509.NewCertPool()
+		if s.systemRoots {
+			if system, err := x509.SystemCertPool(); err == nil {
+				pool = system
+			}
+		}
+		for _, file := range s.caFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return fmt.Errorf("no certificates found in %s", file)
+			}
+		}
+	}
+	var cert *tls.Certificate
+	if s.certFile != "" {
+		pair, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+		if err != nil {
+			return err
+		}
+		cert = &pair
+	}
+	s.pool, s.cert = pool, cert
+	return nil
+}
+
+// current 返回当前证书，距上次检查超过间隔且文件有变化时重新加载。
+func (s *certStore) current() (*x509.CertPool, *tls.Certificate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.checkedAt) >= s.interval {
+		s.checkedAt = time.Now()
+		modTimes := s.stat()
+		changed := len(modTimes) != len(s.modTimes)
+		for file, modTime := range modTimes {
+			changed = changed || !modTime.Equal(s.modTimes[file])
+		}
+		if changed {
+			if err := s.load(); err != nil {
+				s.logger.WithError(err).WithField("files", strings.Join(s.files(), ", ")).
+					Error("reload TLS certificates failed, keep using previous certificates")
+			} else {
+				s.modTimes = modTimes
+			}
+		}
+	}
+	return s.pool, s.cert
+}
+
+func (s *certStore) setLogger(logger *logrus.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logger = logger
+}
+
+// setCertLogger 证书重新加载失败时记录到 logger（实例程序日志）。
+func (c *Config) setCertLogger(logger *logrus.Logger) {
+	var stores []*certStore
+	if c.ServerTLSConfig != nil {
+		stores = append(stores, c.ServerTLSConfig.store)
+	}
+	if cfg := c.HttpClientEnhanceConfig; !c.DisableHttpClientEnhance && cfg != nil {
+		if cfg.TLS != nil {
+			stores = append(stores, cfg.TLS.store)
+		}
+		for _, t := range cfg.HostTLS {
+			stores = append(stores, t.store)
+		}
+	}
+	for _, store := range stores {
+		if store != nil {
+			store.setLogger(logger)
+		}
+	}
+}
+
+// initTLS 校验证书配置并加载证书，已加载的配置不重复加载。
+func (cfg *HttpClientEnhanceConfig) initTLS() error {
+	var errs []error
+	if cfg.TLS != nil && cfg.TLS.store == nil {
+		if err := cfg.TLS.init(); err != nil {
+			errs = append(errs, fmt.Errorf("HttpClientEnhanceConfig.TLS: %w", err))
+		}
+	}
+	for host, t := range cfg.HostTLS {
+		if t == nil {
+			errs = append(errs, fmt.Errorf("HttpClientEnhanceConfig.HostTLS[%q] is nil", host))
+		} else if t.store == nil {
+			if err := t.init(); err != nil {
+				errs = append(errs, fmt.Errorf("HttpClientEnhanceConfig.HostTLS[%q]: %w", host, err))
+			}
+		}
+	}
+	if cfg.Transport != nil && !supportsTLS(cfg.Transport) && cfg.customizedTLS() {
+		errs = append(errs, fmt.Errorf("HttpClientEnhanceConfig.Transport %T does not support TLS settings, "+
+			"use *http.Transport or implement TLSTransport", cfg.Transport))
+	}
+	return errors.Join(errs...)
+}
+
+func (cfg *HttpClientEnhanceConfig) customizedTLS() bool {
+	if cfg.TLS != nil && cfg.TLS.customized() {
+		return true
+	}
+	for _, t := range cfg.HostTLS {
+		if t != nil && t.customized() {
+			return true
+		}
+	}
+	return false
+}
+
+// init 校验配置并加载证书。
+func (t *TLSClientConfig) init() error {
+	var errs []error
+	pins, err := parsePins(t.Pins)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	t.pins = pins
+	store, err := newCertStore(t.CAFiles, true, t.CertFile, t.KeyFile, t.ReloadInterval)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	t.store = store
+	return errors.Join(errs...)
+}
+
+// parsePins 解析公钥固定，返回 SubjectPublicKeyInfo SHA-256 集合。
+func parsePins(pins []string) (map[string]bool, error) {
+	var errs []error
+	hashes := make(map[string]bool, len(pins))
+	for _, pin := range pins {
+		hash, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256/"))
+		if err != nil || len(hash) != sha256.Size {
+			errs = append(errs, fmt.Errorf("invalid pin %q, expected base64 SHA-256 of SubjectPublicKeyInfo", pin))
+			continue
+		}
+		hashes[string(hash)] = true
+	}
+	return hashes, errors.Join(errs...)
+}
+
+// customized 是否需要接管证书验证或提供客户端证书，否则沿用基础传输层的 TLS 配置。
+func (t *TLSClientConfig) customized() bool {
+	return len(t.CAFiles) > 0 || t.CertFile != "" || len(t.Pins) > 0 || t.InsecureSkipVerify || t.ServerName != ""
+}
+
+// clientConfig 基于 base 生成客户端 TLS 配置，证书链及公钥固定由 VerifyConnection 使用可热更新的 CA 证书验证。
+func (t *TLSClientConfig) clientConfig(base *tls.Config) *tls.Config {
+	var c *tls.Config
+	if base != nil {
+		c = base.Clone()
+	} else {
+		c = &tls.Config{}
+	}
+	if t.ServerName != "" {
+		c.ServerName = t.ServerName
+	}
+	c.InsecureSkipVerify = true // 标准验证无法热更新 CA，改由 VerifyConnection 验证
+	c.VerifyConnection = func(cs tls.ConnectionState) error {
+		// IP 地址不会作为 SNI 发送，ServerName 为空，*http.Transport 由 dialTLS 传入实际主机名验证
+		return t.verifyConnection(cs, cs.ServerName)
+	}
+	if t.CertFile != "" {
+		c.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			_, cert := t.store.current()
+			return cert, nil
+		}
+	}
+	return c
+}
+
+// verifyConnection 使用可热更新的 CA 证书验证证书链及 serverName，再校验公钥固定。
+// serverName 为空时无法验证主机名，直接拒绝。
+func (t *TLSClientConfig) verifyConnection(cs tls.ConnectionState, serverName string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tls: server provided no certificates")
+	}
+	if !t.InsecureSkipVerify {
+		if serverName == "" {
+			return errors.New("tls: no server name to verify, set TLSClientConfig.ServerName")
+		}
+		roots, _ := t.store.current()
+		opts := x509.VerifyOptions{DNSName: serverName, Roots: roots, Intermediates: x509.NewCertPool()}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
+			return &tls.CertificateVerificationError{UnverifiedCertificates: cs.PeerCertificates, Err: err}
+		}
+	}
+	if len(t.pins) == 0 {
+		return nil
+	}
+	for _, cert := range cs.PeerCertificates {
+		if hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo); t.pins[string(hash[:])] {
+			return nil
+		}
+	}
+	return fmt.Errorf("tls: no certificate of %s matches pinned public keys", serverName)
+}
+
+// dialTLS 返回 *http.Transport 的 TLS 拨号函数，每个连接使用实际拨号的主机名验证证书（IP 地址同样校验 SAN），
+// 经代理访问时由 TLSClientConfig 的 VerifyConnection 验证。
+func (t *TLSClientConfig) dialTLS(transport *http.Transport) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		// 在拨号时复制，包含 *http.Transport 补充的 NextProtos（h2）
+		config := transport.TLSClientConfig.Clone()
+		if config.ServerName == "" {
+			config.ServerName = host
+		}
+		serverName := config.ServerName
+		config.VerifyConnection = func(cs tls.ConnectionState) error {
+			return t.verifyConnection(cs, serverName)
+		}
+		rawConn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if transport.TLSHandshakeTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, transport.TLSHandshakeTimeout)
+			defer cancel()
+		}
+		conn := tls.Client(rawConn, config)
+		if err := conn.HandshakeContext(ctx); err != nil {
+			_ = rawConn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// TLSTransport 可按证书配置复制的传输层，包装 *http.Transport 的自定义传输层实现该接口后可使用
+// HttpClientEnhanceConfig 的 TLS、HostTLS 配置。证书按握手时的 SNI 验证，访问 IP 地址时需设置 TLSClientConfig.ServerName 。
+type TLSTransport interface {
+	http.RoundTripper
+	// WithTLSConfig 返回使用 configure 生成的 TLS 配置的传输层副本，configure 的参数为当前 TLS 配置（可能为 nil），不修改原传输层。
+	WithTLSConfig(configure func(base *tls.Config) *tls.Config) http.RoundTripper
+}
+
+// supportsTLS 基础传输层能否应用证书配置。
+func supportsTLS(base http.RoundTripper) bool {
+	switch base.(type) {
+	case *http.Transport, TLSTransport:
+		return true
+	}
+	return false
+}
+
+// withClientTLS 返回应用证书配置后的基础传输层副本，无需定制时返回 base 本身。
+func withClientTLS(base http.RoundTripper, t *TLSClientConfig) http.RoundTripper {
+	if t == nil || !t.customized() {
+		return base
+	}
+	switch b := base.(type) {
+	case *http.Transport:
+		transport := b.Clone()
+		transport.TLSClientConfig = t.clientConfig(transport.TLSClientConfig)
+		transport.DialTLSContext = t.dialTLS(transport)
+		return transport
+	case TLSTransport:
+		return b.WithTLSConfig(t.clientConfig)
+	}
+	// 不支持的传输层在 initTLS 时已报错
+	return base
+}
+
+// newTLSTransport 按 HttpClientEnhanceConfig 的 TLS 配置复制基础传输层，配置了 HostTLS 时按主机选择传输层。
+func newTLSTransport(base http.RoundTripper, cfg *HttpClientEnhanceConfig) http.RoundTripper {
+	fallback := withClientTLS(base, cfg.TLS)
+	if len(cfg.HostTLS) == 0 {
+		return fallback
+	}
+	router := &hostTLSTransport{hosts: make(map[string]http.RoundTripper, len(cfg.HostTLS)), fallback: fallback}
+	for host, t := range cfg.HostTLS {
+		router.hosts[strings.ToLower(host)] = withClientTLS(base, t)
+	}
+	return router
+}
+
+// hostTLSTransport 按请求主机选择使用不同 TLS 配置的传输层，先匹配 host:port 再匹配 host 。
+type hostTLSTransport struct {
+	hosts    map[string]http.RoundTripper
+	fallback http.RoundTripper
+}
+
+func (h *hostTLSTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Host)
+	if transport, ok := h.hosts[host]; ok {
+		return transport.RoundTrip(req)
+	}
+	if transport, ok := h.hosts[strings.ToLower(req.URL.Hostname())]; ok {
+		return transport.RoundTrip(req)
+	}
+	return h.fallback.RoundTrip(req)
+}
+
+func (h *hostTLSTransport) CloseIdleConnections() {
+	for _, transport := range h.hosts {
+		closeIdleConnections(transport)
+	}
+	closeIdleConnections(h.fallback)
+}
+
+func closeIdleConnections(transport http.RoundTripper) {
+	if closer, ok := transport.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// init 校验配置并加载证书。
+func (t *ServerTLSConfig) init() error {
+	if t.CertFile == "" || t.KeyFile == "" {
+		return errors.New("CertFile and KeyFile are required")
+	}
+	if t.RequireClientCert && len(t.ClientCAFiles) == 0 {
+		return errors.New("ClientCAFiles is required when RequireClientCert is set")
+	}
+	store, err := newCertStore(t.ClientCAFiles, false, t.CertFile, t.KeyFile, t.ReloadInterval)
+	if err != nil {
+		return err
+	}
+	t.store = store
+	return nil
+}
+
+// serverConfig 服务端 TLS 配置，每次握手使用最新的服务端证书及客户端 CA 证书。
+func (t *ServerTLSConfig) serverConfig() *tls.Config {
+	clientAuth := tls.NoClientCert
+	switch {
+	case t.RequireClientCert:
+		clientAuth = tls.RequireAndVerifyClientCert
+	case len(t.ClientCAFiles) > 0:
+		clientAuth = tls.VerifyClientCertIfGiven
+	}
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			clientCAs, cert := t.store.current()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    clientCAs,
+				ClientAuth:   clientAuth,
+				NextProtos:   []string{"h2", "http/1.1"},
+			}, nil
+		},
+	}
+}
+
+// ClientCertificate 返回已验证的客户端证书（mTLS），客户端未提供证书或未开启验证时返回 nil 。
+func (c *Context) ClientCertificate() *x509.Certificate {
+	if state := c.Request.TLS; state != nil && len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 0 {
+		return state.VerifiedChains[0][0]
+	}
+	return nil
+}
+
+// ClientCertIdentity 返回已验证的客户端证书标识，优先使用 CN（如服务编码），其次使用第一个 URI SAN（如 SPIFFE ID），
+// 没有已验证的客户端证书时返回空。
+func (c *Context) ClientCertIdentity() string {
+	cert := c.ClientCertificate()
+	if cert == nil {
+		return ""
+	}
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	return ""
+}
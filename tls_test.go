@@ -0,0 +1,253 @@
+package ginqq
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pair tls.Certificate
+}
+
+// newTestCert 签发测试证书，parent 为 nil 时生成自签名 CA，否则证书对 localhost 及 127.0.0.1 有效。
+func newTestCert(t *testing.T, cn string, parent *testCert) *testCert {
+	t.Helper()
+	return newTestCertFor(t, cn, parent, []string{"localhost"}, []net.IP{net.IPv4(127, 0, 0, 1)})
+}
+
+func newTestCertFor(t *testing.T, cn string, parent *testCert, dnsNames []string, ips []net.IP) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		tmpl.DNSNames, tmpl.IPAddresses = dnsNames, ips
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, pair: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}}
+}
+
+// write 将证书及私钥写入 dir，返回文件路径。
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func (c *testCert) pin() string {
+	hash := sha256.Sum256(c.cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func newTestTLSServer(t *testing.T, cert *testCert) *httptest.Server {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{cert.pair}}
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestTLSClientVerification(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test-ca", nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	server := newTestCert(t, "server", ca)
+	ts := newTestTLSServer(t, server)
+	other := newTestCert(t, "other", ca)
+
+	get := func(cfg *HttpClientEnhanceConfig) error {
+		if err := cfg.initTLS(); err != nil {
+			t.Fatal(err)
+		}
+		transport := newTLSTransport(originalDefaultTransport.(*http.Transport), cfg)
+		resp, err := (&http.Client{Transport: transport}).Get(ts.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	for _, tt := range []struct {
+		name    string
+		cfg     *HttpClientEnhanceConfig
+		wantErr string
+	}{
+		{"unknown CA", &HttpClientEnhanceConfig{}, "certificate signed by unknown authority"},
+		{"CA bundle", &HttpClientEnhanceConfig{TLS: &TLSClientConfig{CAFiles: []string{caFile}}}, ""},
+		{"pinned", &HttpClientEnhanceConfig{TLS: &TLSClientConfig{CAFiles: []string{caFile}, Pins: []string{server.pin()}}}, ""},
+		{"pin mismatch", &HttpClientEnhanceConfig{TLS: &TLSClientConfig{CAFiles: []string{caFile}, Pins: []string{other.pin()}}},
+			"matches pinned public keys"},
+		{"host override", &HttpClientEnhanceConfig{HostTLS: map[string]*TLSClientConfig{
+			strings.TrimPrefix(ts.URL, "https://"): {CAFiles: []string{caFile}}}}, ""},
+		{"other host override", &HttpClientEnhanceConfig{HostTLS: map[string]*TLSClientConfig{
+			"example.com": {CAFiles: []string{caFile}}}}, "certificate signed by unknown authority"},
+		{"skip verify", &HttpClientEnhanceConfig{TLS: &TLSClientConfig{InsecureSkipVerify: true}}, ""},
+	} {
+		err := get(tt.cfg)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: err %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// IP 地址访问时同样校验证书 SAN
+	dnsOnly := newTestTLSServer(t, newTestCertFor(t, "dns-only", ca, []string{"localhost"}, nil))
+	cfg := &HttpClientEnhanceConfig{TLS: &TLSClientConfig{CAFiles: []string{caFile}}}
+	if err := cfg.initTLS(); err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: newTLSTransport(originalDefaultTransport.(*http.Transport), cfg)}
+	if _, err := client.Get(dnsOnly.URL); err == nil || !strings.Contains(err.Error(), "127.0.0.1") {
+		t.Errorf("certificate without IP SAN: err %v", err)
+	}
+
+	if err := (&HttpClientEnhanceConfig{TLS: &TLSClientConfig{Pins: []string{"bad"}, CertFile: "client.crt"}}).initTLS(); err == nil ||
+		!strings.Contains(err.Error(), "invalid pin") || !strings.Contains(err.Error(), "set together") {
+		t.Errorf("invalid config: %v", err)
+	}
+}
+
+func TestServerTLSClientCert(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test-ca", nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newTestCert(t, "server", ca).write(t, dir, "server")
+	clientCert, clientKey := newTestCert(t, "B186010101", ca).write(t, dir, "client")
+
+	serverTLS := &ServerTLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFiles: []string{caFile}, RequireClientCert: true}
+	if err := serverTLS.init(); err != nil {
+		t.Fatal(err)
+	}
+	g := newTestGinQQ(t, &Config{DisableTransactionLog: true}, func([]byte) {})
+	g.GET("/whoami", func(c *Context) { c.String(http.StatusOK, c.ClientCertIdentity()) })
+	ts := httptest.NewUnstartedServer(g.Engine)
+	ts.TLS = serverTLS.serverConfig()
+	ts.StartTLS()
+	defer ts.Close()
+
+	get := func(tlsCfg *TLSClientConfig) (string, error) {
+		cfg := &HttpClientEnhanceConfig{TLS: tlsCfg}
+		if err := cfg.initTLS(); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := (&http.Client{Transport: newTLSTransport(originalDefaultTransport.(*http.Transport), cfg)}).Get(ts.URL + "/whoami")
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	if identity, err := get(&TLSClientConfig{CAFiles: []string{caFile}, CertFile: clientCert, KeyFile: clientKey}); err != nil || identity != "B186010101" {
+		t.Errorf("client identity %q, err %v", identity, err)
+	}
+	if _, err := get(&TLSClientConfig{CAFiles: []string{caFile}}); err == nil {
+		t.Error("request without client certificate accepted")
+	}
+	otherCert, otherKey := newTestCert(t, "B186020202", newTestCert(t, "other-ca", nil)).write(t, dir, "other")
+	if identity, err := get(&TLSClientConfig{CAFiles: []string{caFile}, CertFile: otherCert, KeyFile: otherKey}); err == nil {
+		t.Errorf("client certificate from unconfigured CA accepted as %q", identity)
+	}
+	expected := x509.NewCertPool()
+	expected.AddCert(ca.cert)
+	if clientCAs, _ := serverTLS.store.current(); !clientCAs.Equal(expected) {
+		t.Error("client CA pool contains certificates other than ClientCAFiles")
+	}
+	if err := (&ServerTLSConfig{CertFile: certFile, KeyFile: keyFile, RequireClientCert: true}).init(); err == nil {
+		t.Error("RequireClientCert without ClientCAFiles accepted")
+	}
+}
+
+func TestCertStoreReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test-ca", nil)
+	certFile, keyFile := newTestCert(t, "v1", ca).write(t, dir, "server")
+	store, err := newCertStore(nil, false, certFile, keyFile, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger, hook := test.NewNullLogger()
+	store.setLogger(logger)
+
+	// 文件写坏时继续使用原证书
+	if err := os.WriteFile(certFile, []byte("broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Second)
+	_ = os.Chtimes(certFile, future, future)
+	time.Sleep(2 * time.Millisecond)
+	commonName := func() string {
+		_, cert := store.current()
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf.Subject.CommonName
+	}
+	if cn := commonName(); cn != "v1" {
+		t.Fatalf("certificate %s after failed reload, want v1", cn)
+	}
+	if entry := hook.LastEntry(); entry == nil || entry.Level != logrus.ErrorLevel {
+		t.Errorf("reload failure not logged at ERROR: %v", entry)
+	}
+
+	newTestCert(t, "v2", ca).write(t, dir, "server")
+	future = future.Add(time.Second)
+	_ = os.Chtimes(certFile, future, future)
+	_ = os.Chtimes(keyFile, future, future)
+	time.Sleep(2 * time.Millisecond)
+	if cn := commonName(); cn != "v2" {
+		t.Errorf("certificate %s after rotation, want v2", cn)
+	}
+}
@@ -0,0 +1,263 @@
+package ginqq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	XTraceparent = "traceparent" // W3C Trace Context
+	XTracestate  = "tracestate"
+
+	xSpan = "ginqq.span" // ctx 中保存当前服务端 Span 的 key
+)
+
+// Span 一次调用的链路片段，结束后交由 SpanExporter 导出。
+type Span struct {
+	TraceID       string                 `json:"trace_id"`
+	SpanID        string                 `json:"span_id"`
+	ParentSpanID  string                 `json:"parent_span_id,omitempty"`
+	TraceState    string                 `json:"trace_state,omitempty"`
+	Name          string                 `json:"name"`
+	Kind          string                 `json:"kind"` // server、client
+	Service       string                 `json:"service"`
+	StartTime     time.Time              `json:"start_time"`
+	EndTime       time.Time              `json:"end_time"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	StatusCode    string                 `json:"status_code"` // unset、ok、error
+	StatusMessage string                 `json:"status_message,omitempty"`
+
+	sampled bool
+}
+
+// SetAttribute 设置 Span 属性。
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]interface{})
+	}
+	s.Attributes[key] = value
+}
+
+// Traceparent 返回以当前 Span 为父节点的 W3C traceparent 头。
+func (s *Span) Traceparent() string {
+	flags := "00"
+	if s.sampled {
+		flags = "01"
+	}
+	return "00-" + s.TraceID + "-" + s.SpanID + "-" + flags
+}
+
+// SpanExporter 链路导出器，可自定义实现对接不同的链路系统。
+type SpanExporter interface {
+	ExportSpans(ctx context.Context, spans []*Span) error
+	Shutdown(ctx context.Context) error
+}
+
+// tracer 收集结束的 Span，按批次异步导出。
+type tracer struct {
+	service  string
+	exporter SpanExporter
+
+	queue     chan *Span
+	batchSize int
+	interval  time.Duration
+	dropped   atomic.Uint64 // 队列已满时丢弃的 Span 数
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func newTracer(c *Config) *tracer {
+	t := &tracer{
+		service:   c.Identity().Name(),
+		exporter:  c.TracingConfig.Exporter,
+		queue:     make(chan *Span, c.TracingConfig.QueueSize),
+		batchSize: c.TracingConfig.BatchSize,
+		interval:  c.TracingConfig.FlushInterval,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+func (t *tracer) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, t.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.exporter.ExportSpans(context.Background(), batch); err != nil {
+			fmt.Printf("[Tracing] export %d spans failed: %v\n", len(batch), err)
+		}
+		batch = make([]*Span, 0, t.batchSize)
+	}
+
+	for {
+		select {
+		case span := <-t.queue:
+			batch = append(batch, span)
+			if len(batch) >= t.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.stop:
+			for {
+				select {
+				case span := <-t.queue:
+					batch = append(batch, span)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// export 提交结束的 Span，队列已满时丢弃并计数，避免阻塞请求。
+func (t *tracer) export(span *Span) {
+	if !span.sampled {
+		return
+	}
+	select {
+	case t.queue <- span:
+	default:
+		t.dropped.Add(1)
+	}
+}
+
+// shutdown 导出队列中剩余的 Span 并关闭导出器。
+func (t *tracer) shutdown(ctx context.Context) error {
+	t.stopOnce.Do(func() { close(t.stop) })
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return t.exporter.Shutdown(ctx)
+}
+
+// handle 为每个请求创建服务端 Span，作为中间件使用。
+func (t *tracer) handle(c *Context) {
+	span := &Span{
+		SpanID:    newSpanID(),
+		Name:      c.Request.Method + " " + c.FullPath(),
+		Kind:      "server",
+		Service:   t.service,
+		StartTime: time.Now(),
+		sampled:   true,
+	}
+
+	if traceID, parentSpanID, flags, ok := parseTraceparent(c.GetHeader(XTraceparent)); ok {
+		span.TraceID = traceID
+		span.ParentSpanID = parentSpanID
+		span.TraceState = c.GetHeader(XTracestate)
+		span.sampled = flags&0x01 == 0x01
+	} else if traceID := c.GetTraceID(); isValidTraceID(traceID) {
+		span.TraceID = traceID
+	} else {
+		// 历史 Trace-ID 不符合 W3C 格式时重新生成，并保留原值便于关联
+		span.TraceID = newTraceID()
+		span.SetAttribute("ginqq.legacy_trace_id", traceID)
+	}
+
+	c.Set(xSpan, span)
+	c.Header(XTraceparent, span.Traceparent())
+	if span.TraceState != "" {
+		c.Header(XTracestate, span.TraceState)
+	}
+	c.Header(XTraceID, c.GetTraceID())
+
+	c.Next()
+
+	span.EndTime = time.Now()
+	status := c.Writer.Status()
+	span.SetAttribute("http.method", c.Request.Method)
+	span.SetAttribute("http.route", c.FullPath())
+	span.SetAttribute("http.status_code", status)
+	span.SetAttribute("ginqq.method_code", c.GetMethodCode())
+	span.SetAttribute("ginqq.method_name", c.GetMethodName())
+	span.SetAttribute("ginqq.fcode", c.GetFCode())
+	span.SetAttribute("ginqq.transaction_id", c.GetTransactionID())
+	if status >= 500 {
+		span.StatusCode = "error"
+		span.StatusMessage = strconv.Itoa(status)
+	} else if len(c.Errors) > 0 {
+		span.StatusCode = "error"
+		span.StatusMessage = c.Errors.String()
+	} else {
+		span.StatusCode = "ok"
+	}
+
+	t.export(span)
+}
+
+// parseTraceparent 解析 W3C traceparent 头：version-trace_id-parent_id-flags 。
+func parseTraceparent(value string) (traceID, spanID string, flags byte, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return "", "", 0, false
+	}
+	version, traceID, spanID, flagsHex := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || !isLowerHex(version) || version == "ff" {
+		return "", "", 0, false
+	}
+	// 版本 00 固定为 4 段，更高版本允许追加字段
+	if version == "00" && len(parts) != 4 {
+		return "", "", 0, false
+	}
+	if !isValidTraceID(traceID) || !isValidSpanID(spanID) {
+		return "", "", 0, false
+	}
+	if len(flagsHex) != 2 || !isLowerHex(flagsHex) {
+		return "", "", 0, false
+	}
+	f, _ := strconv.ParseUint(flagsHex, 16, 8)
+	return traceID, spanID, byte(f), true
+}
+
+func isValidTraceID(id string) bool {
+	return len(id) == 32 && isLowerHex(id) && strings.Trim(id, "0") != ""
+}
+
+func isValidSpanID(id string) bool {
+	return len(id) == 16 && isLowerHex(id) && strings.Trim(id, "0") != ""
+}
+
+func isLowerHex(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func newTraceID() string {
+	return randomHex(16)
+}
+
+func newSpanID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,185 @@
+package ginqq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// JSONLinesExporter 将 Span 以 JSON Lines 格式写入 io.Writer，每行一个 Span。
+type JSONLinesExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewJSONLinesExporter(w io.Writer) *JSONLinesExporter {
+	return &JSONLinesExporter{w: w}
+}
+
+func (e *JSONLinesExporter) ExportSpans(_ context.Context, spans []*Span) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, span := range spans {
+		if err := encoder.Encode(span); err != nil {
+			return err
+		}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err := e.w.Write(buf.Bytes())
+	return err
+}
+
+func (e *JSONLinesExporter) Shutdown(context.Context) error {
+	if closer, ok := e.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// OTLPHTTPExporter 通过 OTLP/HTTP（JSON 编码）将 Span 发送到链路采集器，
+// 如 OpenTelemetry Collector 的 http://<host>:4318/v1/traces 。
+type OTLPHTTPExporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// NewOTLPHTTPExporter 创建 OTLP/HTTP 导出器，endpoint 为完整的 traces 接收地址，headers 为附加请求头（如鉴权）。
+func NewOTLPHTTPExporter(endpoint string, headers map[string]string) *OTLPHTTPExporter {
+	return &OTLPHTTPExporter{
+		endpoint: endpoint,
+		headers:  headers,
+		// 使用原始传输层，避免导出请求再经过 HttpEnhance 增强链
+		client: &http.Client{Transport: originalDefaultTransport, Timeout: 10 * time.Second},
+	}
+}
+
+func (e *OTLPHTTPExporter) ExportSpans(ctx context.Context, spans []*Span) error {
+	body, err := json.Marshal(otlpTraces(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("otlp collector responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (e *OTLPHTTPExporter) Shutdown(context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// otlpTraces 按 OTLP JSON 编码组装 ExportTraceServiceRequest，Span 按服务名分组。
+func otlpTraces(spans []*Span) map[string]interface{} {
+	byService := make(map[string][]interface{})
+	var services []string
+	for _, span := range spans {
+		if _, ok := byService[span.Service]; !ok {
+			services = append(services, span.Service)
+		}
+		byService[span.Service] = append(byService[span.Service], otlpSpan(span))
+	}
+
+	resourceSpans := make([]interface{}, 0, len(services))
+	for _, service := range services {
+		resourceSpans = append(resourceSpans, map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []interface{}{otlpAttribute("service.name", service)},
+			},
+			"scopeSpans": []interface{}{
+				map[string]interface{}{
+					"scope": map[string]interface{}{"name": "ginqq"},
+					"spans": byService[service],
+				},
+			},
+		})
+	}
+	return map[string]interface{}{"resourceSpans": resourceSpans}
+}
+
+func otlpSpan(span *Span) map[string]interface{} {
+	kind := 1 // SPAN_KIND_INTERNAL
+	switch span.Kind {
+	case "server":
+		kind = 2
+	case "client":
+		kind = 3
+	}
+	statusCode := 0 // STATUS_CODE_UNSET
+	switch span.StatusCode {
+	case "ok":
+		statusCode = 1
+	case "error":
+		statusCode = 2
+	}
+
+	keys := make([]string, 0, len(span.Attributes))
+	for k := range span.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attributes := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		attributes = append(attributes, otlpAttribute(k, span.Attributes[k]))
+	}
+
+	s := map[string]interface{}{
+		"traceId":           span.TraceID,
+		"spanId":            span.SpanID,
+		"name":              span.Name,
+		"kind":              kind,
+		"startTimeUnixNano": strconv.FormatInt(span.StartTime.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+		"attributes":        attributes,
+		"status":            map[string]interface{}{"code": statusCode, "message": span.StatusMessage},
+	}
+	if span.ParentSpanID != "" {
+		s["parentSpanId"] = span.ParentSpanID
+	}
+	if span.TraceState != "" {
+		s["traceState"] = span.TraceState
+	}
+	return s
+}
+
+func otlpAttribute(key string, value interface{}) map[string]interface{} {
+	var v map[string]interface{}
+	switch val := value.(type) {
+	case bool:
+		v = map[string]interface{}{"boolValue": val}
+	case int:
+		v = map[string]interface{}{"intValue": strconv.Itoa(val)}
+	case int64:
+		v = map[string]interface{}{"intValue": strconv.FormatInt(val, 10)}
+	case float64:
+		v = map[string]interface{}{"doubleValue": val}
+	case string:
+		v = map[string]interface{}{"stringValue": val}
+	default:
+		v = map[string]interface{}{"stringValue": fmt.Sprintf("%v", val)}
+	}
+	return map[string]interface{}{"key": key, "value": v}
+}
@@ -0,0 +1,117 @@
+package ginqq
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		value   string
+		ok      bool
+		traceID string
+		spanID  string
+		flags   byte
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", 1},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra", true, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", 0},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, "", "", 0},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, "", "", 0},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, "", "", 0},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, "", "", 0},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, "", "", 0},
+		{"", false, "", "", 0},
+	}
+	for _, tt := range tests {
+		traceID, spanID, flags, ok := parseTraceparent(tt.value)
+		if ok != tt.ok || traceID != tt.traceID || spanID != tt.spanID || flags != tt.flags {
+			t.Errorf("parseTraceparent(%q) = %q, %q, %d, %v", tt.value, traceID, spanID, flags, ok)
+		}
+	}
+}
+
+func TestTracingOTLPExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	received := make(chan map[string]interface{}, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("invalid otlp payload: %v", err)
+		}
+		received <- payload
+	}))
+	defer collector.Close()
+
+	tr := newTracer(&Config{
+		SvcCode: "A186010101",
+		AppName: "ginqq_test",
+		TracingConfig: &TracingConfig{
+			Exporter:      NewOTLPHTTPExporter(collector.URL+"/v1/traces", nil),
+			QueueSize:     16,
+			BatchSize:     16,
+			FlushInterval: time.Hour,
+		},
+	})
+
+	engine := gin.New()
+	engine.Use(convertToGinHandlers([]func(*Context){tr.handle})...)
+	engine.GET("/users/:id", convertToGinHandlers([]func(*Context){MethodCode("I00101"), func(c *Context) {
+		c.Status(http.StatusOK)
+	}})...)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set(XTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(XTracestate, "vendor=value")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	traceID, spanID, _, ok := parseTraceparent(w.Header().Get(XTraceparent))
+	if !ok || traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || spanID == "00f067aa0ba902b7" {
+		t.Fatalf("unexpected response traceparent %q", w.Header().Get(XTraceparent))
+	}
+	if got := w.Header().Get(XTraceID); got != traceID {
+		t.Errorf("Trace-ID = %q, want %q", got, traceID)
+	}
+
+	if err := tr.shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	payload := <-received
+	serialized, _ := json.Marshal(payload)
+	for _, want := range []string{
+		`"traceId":"4bf92f3577b34da6a3ce929d0e0e4736"`,
+		`"spanId":"` + spanID + `"`,
+		`"parentSpanId":"00f067aa0ba902b7"`,
+		`"traceState":"vendor=value"`,
+		`{"key":"http.route","value":{"stringValue":"/users/:id"}}`,
+		`{"key":"ginqq.method_code","value":{"stringValue":"I00101"}}`,
+		`{"key":"http.status_code","value":{"intValue":"200"}}`,
+		`{"key":"service.name","value":{"stringValue":"a186010101_ginqq_test"}}`,
+	} {
+		if !strings.Contains(string(serialized), want) {
+			t.Errorf("otlp payload missing %s\n%s", want, serialized)
+		}
+	}
+}
+
+func TestTracerDropped(t *testing.T) {
+	// 未启动导出协程，队列满后的 Span 计入丢弃数
+	tr := &tracer{queue: make(chan *Span, 1)}
+	g := &GinQQ{tracer: tr}
+	for i := 0; i < 3; i++ {
+		tr.export(&Span{sampled: true})
+	}
+	tr.export(&Span{})
+	if got := g.SpansDropped(); got != 2 {
+		t.Errorf("SpansDropped = %d, want 2", got)
+	}
+}
@@ -3,28 +3,36 @@ package ginqq
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"net/http"
 	"reflect"
 	"runtime"
 	"runtime/debug"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
 type TransactionLog struct {
-	ctx *Context
+	ctx    *Context
+	engine *GinQQ
 
 	requestTime  time.Time
 	responseTime time.Time
 
+	request                *requestCapture
+	response               *responseCaptureWriter
+	responsePayload        interface{}
+	responsePayloadCrossed bool
+	skipPayload            bool // 不记录请求体、响应体
+
 	AppName             string `json:"app_name"`
 	Level               string `json:"level"`
 	LogTime             string `json:"log_time"`
 	Logger              string `json:"logger"`
 	Thread              string `json:"thread"`
 	TransactionID       string `json:"transaction_id"`
+	ParentTransactionID string `json:"parent_transaction_id"`
+	TraceID             string `json:"trace_id"`
 	DialogType          string `json:"dialog_type"`
 	Address             string `json:"address"`
 	FCode               string `json:"fcode"`
@@ -56,11 +64,88 @@ type TransactionLog struct {
 	User                string `json:"user"`
 	Tag                 string `json:"tag"`
 	ServiceLine         string `json:"service_line"`
+	SecurityEvent       string `json:"security_event"` // 命中的安全规则，JSON 数组
+}
+
+// transactionLogField 流水字段提取器。
+type transactionLogField struct {
+	name    string
+	extract func(*TransactionLog) *TransactionLog
+}
+
+// builtinTransactionLogFields 内置的内部流水字段提取器，请求结束后在请求协程中按顺序执行，
+// 之后执行 TransactionLogConfig.Fields 中的自定义提取器。
+var builtinTransactionLogFields = []transactionLogField{
+	{"AppName", (*TransactionLog).GetAppName},
+	{"Level", (*TransactionLog).GetLevel},
+	{"LogTime", (*TransactionLog).GetLogTime},
+	{"Logger", (*TransactionLog).GetLogger},
+	{"Thread", (*TransactionLog).GetThread},
+	{"TransactionID", (*TransactionLog).GetTransactionID},
+	{"TraceID", (*TransactionLog).GetTraceID},
+	{"DialogType", (*TransactionLog).GetDialogType},
+	{"Address", (*TransactionLog).GetAddress},
+	{"FCode", (*TransactionLog).GetFCode},
+	{"TCode", (*TransactionLog).GetTCode},
+	{"MethodCode", (*TransactionLog).GetMethodCode},
+	{"MethodName", (*TransactionLog).GetMethodName},
+	{"HTTPMethod", (*TransactionLog).GetHTTPMethod},
+	{"RequestTime", (*TransactionLog).GetRequestTime},
+	{"RequestHeaders", (*TransactionLog).GetRequestHeaders},
+	{"RequestPayload", (*TransactionLog).GetRequestPayload},
+	{"ResponseTime", (*TransactionLog).GetResponseTime},
+	{"ResponseHeaders", (*TransactionLog).GetResponseHeaders},
+	{"ResponsePayload", (*TransactionLog).GetResponsePayload},
+	{"ResponseRemark", (*TransactionLog).GetResponseRemark},
+	{"ResponseCode", (*TransactionLog).GetResponseCode},
+	{"HTTPStatusCode", (*TransactionLog).GetHTTPStatusCode},
+	{"OrderID", (*TransactionLog).GetOrderID},
+	{"ProvinceCodeAndCityCode", (*TransactionLog).GetProvinceCodeAndCityCode},
+	{"TotalTime", (*TransactionLog).GetTotalTime},
+	{"ErrorCode", (*TransactionLog).GetErrorCode},
+	{"RequestIP", (*TransactionLog).GetRequestIP},
+	{"HostIP", (*TransactionLog).GetHostIP},
+	{"Hostname", (*TransactionLog).GetHostname},
+	{"Account", (*TransactionLog).GetAccount},
+	{"ResponseAccount", (*TransactionLog).GetResponseAccount},
+	{"User", (*TransactionLog).GetUser},
+	{"Tag", (*TransactionLog).GetTag},
+	{"ServiceLine", (*TransactionLog).GetServiceLine},
+	{"SecurityEvent", (*TransactionLog).GetSecurityEvent},
+}
+
+// TransactionLogField 自定义流水字段提取器，请求结束后在请求协程中执行，
+// 可通过 TransactionLog.Context 读取请求上下文填充字段（如 User、Tag、ServiceLine）。
+type TransactionLogField struct {
+	Name    string // 提取器名称，提取器 panic 时用于定位
+	Extract func(*TransactionLog)
+}
+
+// newTransactionLogFields 返回实例的流水字段提取器：内置提取器后接自定义提取器。
+func newTransactionLogFields(custom []TransactionLogField) []transactionLogField {
+	fields := make([]transactionLogField, 0, len(builtinTransactionLogFields)+len(custom))
+	fields = append(fields, builtinTransactionLogFields...)
+	for _, field := range custom {
+		extract := field.Extract
+		fields = append(fields, transactionLogField{
+			name: field.Name,
+			extract: func(log *TransactionLog) *TransactionLog {
+				extract(log)
+				return log
+			},
+		})
+	}
+	return fields
 }
 
 // DispatchTransactionLog 调度内部流水日志，作为中间件使用。
 func DispatchTransactionLog(c *Context) {
-	log := &TransactionLog{ctx: c}
+	engine := c.Engine()
+	if engine == nil || engine.transactionLogWriter == nil {
+		c.Next()
+		return
+	}
+	log := &TransactionLog{ctx: c, engine: engine}
 
 	log.before()
 
@@ -68,48 +153,102 @@ func DispatchTransactionLog(c *Context) {
 	c.Next()
 	log.responseTime = time.Now()
 
-	go func() {
-		log.after()
-		msg, _ := json.Marshal(log)
-		logger.Info(string(msg))
-	}()
+	// 请求结束前提取字段，gin.Context 在请求结束后会被复用，不能在其他协程中访问
+	log.after()
+	log.mask()
+	if msg, err := json.Marshal(log); err == nil {
+		engine.transactionLogWriter.Write(msg)
+	}
 }
 
+// Context 返回流水对应的请求上下文，供自定义字段提取器使用，外部流水返回 nil 。
+func (log *TransactionLog) Context() *Context {
+	return log.ctx
+}
+
+// before 在请求处理前包装请求体和响应，请求处理过程中边读写边记录。
+// 响应始终包装，不记录响应体时仍用于提取 response_code 等字段。
 func (log *TransactionLog) before() {
 	defer deferRecover()
-	log.GetRequestPayload()
+	opts := log.engine.capture
+	if log.engine.logControl.mayCapturePayload() && log.ctx.Request.Body != nil && log.ctx.Request.Body != http.NoBody {
+		log.request = newRequestCapture(log.ctx.Request, opts)
+		log.ctx.Request.Body = log.request
+	}
+	log.response = newResponseCaptureWriter(log.ctx.Writer, opts)
+	log.ctx.Writer = log.response
 }
 
 func (log *TransactionLog) after() {
+	// Method-Code 可能在请求处理过程中设置，请求结束后再判断是否记录请求体、响应体
+	log.skipPayload = !log.engine.logControl.capturePayload(log.ctx.GetMethodCode())
+	for _, field := range log.engine.transactionLogFields {
+		log.extract(field)
+	}
+}
+
+// mask 对可能包含敏感信息的字段脱敏，在所有字段提取完成后、序列化前执行。
+func (log *TransactionLog) mask() {
 	defer deferRecover()
+	m := log.engine.masker
+	if m == nil {
+		return
+	}
+	log.RequestHeaders = m.maskHeaders(log.RequestHeaders)
+	log.RequestPayload = m.maskJSON(log.RequestPayload)
+	log.ResponseHeaders = m.maskHeaders(log.ResponseHeaders)
+	log.ResponsePayload = m.maskJSON(log.ResponsePayload)
+	v := reflect.ValueOf(log).Elem()
+	for _, i := range transactionLogTextFields {
+		field := v.Field(i)
+		field.SetString(m.maskString(field.String()))
+	}
+}
 
-	var wg sync.WaitGroup
-
-	v := reflect.ValueOf(log)
-	typ := v.Type()
-
-	for i := 0; i < typ.NumMethod(); i++ {
-		methodName := typ.Method(i).Name
-		if strings.HasPrefix(methodName, "Get") && methodName != "GetRequestPayload" {
-			wg.Add(1)
-			go func(m reflect.Value, name string) {
-				defer func() {
-					wg.Done()
-					if err := recover(); err != nil {
-						fmt.Printf("[TransactionLog] %s panic: %v\n", name, err)
-						debug.PrintStack()
-					}
-				}()
-				m.Call(nil)
-			}(v.Method(i), methodName)
+// transactionLogTextFields 按正则规则脱敏的字段下标：除请求头、响应头、请求数据、响应数据外的所有字符串字段，
+// 包括 TransactionLogConfig.Fields 填充的字段。
+var transactionLogTextFields = func() []int {
+	var fields []int
+	t := reflect.TypeOf(TransactionLog{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		switch field.Name {
+		case "RequestHeaders", "RequestPayload", "ResponseHeaders", "ResponsePayload":
+			continue
+		}
+		if field.IsExported() && field.Type.Kind() == reflect.String {
+			fields = append(fields, i)
 		}
 	}
+	return fields
+}()
+
+func (log *TransactionLog) extract(field transactionLogField) {
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Printf("[TransactionLog] %s panic: %v\n", field.name, err)
+			debug.PrintStack()
+		}
+	}()
+	field.extract(log)
+}
 
-	wg.Wait()
+// crossedResponsePayload 返回经 CrossJson 转换的响应数据，多个字段共用，只转换一次。
+// 未通过 Context.JSON 等方法响应时，使用实际写出的 JSON 响应体。
+func (log *TransactionLog) crossedResponsePayload() interface{} {
+	if !log.responsePayloadCrossed {
+		if responsePayload := log.ctx.GetResponsePayload(); responsePayload != nil {
+			log.responsePayload = CrossJson(responsePayload)
+		} else if log.response != nil {
+			log.responsePayload = log.response.data()
+		}
+		log.responsePayloadCrossed = true
+	}
+	return log.responsePayload
 }
 
 func (log *TransactionLog) GetAppName() *TransactionLog {
-	log.AppName = strings.ToLower(cnf.SvcCode) + "_" + cnf.AppName + "_info"
+	log.AppName = log.engine.Config.Identity().Name() + "_info"
 	return log
 }
 
@@ -142,6 +281,11 @@ func (log *TransactionLog) GetTransactionID() *TransactionLog {
 	return log
 }
 
+func (log *TransactionLog) GetTraceID() *TransactionLog {
+	log.TraceID = log.ctx.GetTraceID()
+	return log
+}
+
 func (log *TransactionLog) GetDialogType() *TransactionLog {
 	log.DialogType = "in"
 	return log
@@ -166,7 +310,7 @@ func (log *TransactionLog) GetFCode() *TransactionLog {
 }
 
 func (log *TransactionLog) GetTCode() *TransactionLog {
-	log.TCode = cnf.SvcCode
+	log.TCode = log.engine.Config.Identity().SvcCode
 	return log
 }
 
@@ -191,37 +335,24 @@ func (log *TransactionLog) GetRequestTime() *TransactionLog {
 }
 
 func (log *TransactionLog) GetRequestHeaders() *TransactionLog {
-	headers := make(map[string]string)
-	for k, v := range log.ctx.Request.Header {
-		headers[k] = strings.Join(v, ", ")
-	}
-	headersSerialized, _ := json.Marshal(headers)
-	log.RequestHeaders = string(headersSerialized)
+	log.RequestHeaders = serializeHeaders(log.ctx.Request.Header)
 	return log
 }
 
-// GetRequestPayload 获取请求数据，仅获取查询参数，表单数据，JSON数据。
+// GetRequestPayload 获取请求数据，合并查询参数、表单数据、JSON数据，请求体超出记录上限时截断，
+// multipart 请求记录字段值和文件摘要，二进制内容只记录类型和大小。不记录请求体时只记录查询参数。
 func (log *TransactionLog) GetRequestPayload() *TransactionLog {
-	requestPayload := make(map[string]interface{})
-
-	// Get query params and form data.
-	_ = log.ctx.Request.ParseForm()
-	for key, values := range log.ctx.Request.Form {
-		if len(values) == 1 {
-			requestPayload[key] = values[0]
-		} else {
-			requestPayload[key] = values
-		}
-	}
-
-	// Get json data.
-	requestBody, _ := log.ctx.GetRawDataReusable()
-	if len(requestBody) != 0 {
-		_ = json.Unmarshal(requestBody, &requestPayload)
+	var requestPayload map[string]interface{}
+	if log.request != nil && !log.skipPayload {
+		log.request.complete()
+		requestPayload = log.request.payload(log.ctx.Request)
+	} else {
+		requestPayload = make(map[string]interface{})
+		mergeValues(requestPayload, log.ctx.Request.URL.Query())
 	}
 
-	requestPayloadSerialized, _ := json.Marshal(requestPayload)
-	log.RequestPayload = string(requestPayloadSerialized)
+	requestPayloadSerialized, _ := marshalNoEscape(requestPayload)
+	log.RequestPayload = requestPayloadSerialized
 
 	return log
 }
@@ -232,32 +363,39 @@ func (log *TransactionLog) GetResponseTime() *TransactionLog {
 }
 
 func (log *TransactionLog) GetResponseHeaders() *TransactionLog {
-	headers := make(map[string]string)
-	for k, v := range log.ctx.Writer.Header() {
-		headers[k] = strings.Join(v, ", ")
-	}
-	headersSerialized, _ := json.Marshal(headers)
-	log.ResponseHeaders = string(headersSerialized)
+	log.ResponseHeaders = serializeHeaders(log.ctx.Writer.Header())
 	return log
 }
 
+// GetResponsePayload 获取响应数据，优先使用 Context.JSON 等方法传入的对象，其次使用实际写出的响应体。
+// 不记录响应体时为 {} 。
 func (log *TransactionLog) GetResponsePayload() *TransactionLog {
-	if responsePayload := log.ctx.GetResponsePayload(); responsePayload != nil {
-		responsePayloadSerialized, _ := json.Marshal(responsePayload)
-		log.ResponsePayload = string(responsePayloadSerialized)
+	if log.skipPayload {
+		log.ResponsePayload = "{}"
+	} else if responsePayload := log.ctx.GetResponsePayload(); responsePayload != nil {
+		log.ResponsePayload = log.engine.capture.objectPayload(responsePayload)
+	} else if log.response != nil {
+		log.ResponsePayload = log.response.payload()
 	} else {
 		log.ResponsePayload = "{}"
 	}
 	return log
 }
 
+// GetResponseRemark 获取响应备注，通过 Context.Fail 响应时为错误备注。
 func (log *TransactionLog) GetResponseRemark() *TransactionLog {
+	if result := log.ctx.getResponseResult(); result != nil {
+		log.ResponseRemark = result.remark
+	}
 	return log
 }
 
+// GetResponseCode 获取响应码，通过 Context.Success、Context.Fail 响应时使用其响应码，否则从响应数据中查找 code 字段。
 func (log *TransactionLog) GetResponseCode() *TransactionLog {
-	if responsePayload := log.ctx.GetResponsePayload(); responsePayload != nil {
-		log.ResponseCode = FuzzyGet(CrossJson(responsePayload), "code")
+	if result := log.ctx.getResponseResult(); result != nil {
+		log.ResponseCode = result.code
+	} else if responsePayload := log.crossedResponsePayload(); responsePayload != nil {
+		log.ResponseCode = FuzzyGet(responsePayload, "code")
 	}
 	return log
 }
@@ -268,8 +406,8 @@ func (log *TransactionLog) GetHTTPStatusCode() *TransactionLog {
 }
 
 func (log *TransactionLog) GetOrderID() *TransactionLog {
-	if responsePayload := log.ctx.GetResponsePayload(); responsePayload != nil {
-		log.OrderID = FuzzyGetMany(CrossJson(responsePayload), []string{"order_id", "ht_id"})
+	if responsePayload := log.crossedResponsePayload(); responsePayload != nil {
+		log.OrderID = FuzzyGetMany(responsePayload, []string{"order_id", "ht_id"})
 	}
 	return log
 }
@@ -277,8 +415,7 @@ func (log *TransactionLog) GetOrderID() *TransactionLog {
 func (log *TransactionLog) GetProvinceCodeAndCityCode() *TransactionLog {
 	var requestPayload map[string]interface{}
 	_ = json.Unmarshal([]byte(log.RequestPayload), &requestPayload)
-	responsePayload := CrossJson(log.ctx.GetResponsePayload())
-	mergedPayload := []interface{}{requestPayload, responsePayload}
+	mergedPayload := []interface{}{requestPayload, log.crossedResponsePayload()}
 	log.ProvinceCode = FuzzyGet(mergedPayload, "province_code")
 	log.CityCode = FuzzyGet(mergedPayload, "city_code")
 	return log
@@ -289,7 +426,11 @@ func (log *TransactionLog) GetTotalTime() *TransactionLog {
 	return log
 }
 
+// GetErrorCode 获取错误码，通过 Context.Fail 响应时为其响应码。
 func (log *TransactionLog) GetErrorCode() *TransactionLog {
+	if result := log.ctx.getResponseResult(); result != nil && result.failed {
+		log.ErrorCode = result.code
+	}
 	return log
 }
 
@@ -299,16 +440,12 @@ func (log *TransactionLog) GetRequestIP() *TransactionLog {
 }
 
 func (log *TransactionLog) GetHostIP() *TransactionLog {
-	if hostIP, err := GetHostIP(); err == nil {
-		log.HostIP = hostIP
-	}
+	log.HostIP = localHost().ip
 	return log
 }
 
 func (log *TransactionLog) GetHostname() *TransactionLog {
-	if hostname, err := os.Hostname(); err == nil {
-		log.Hostname = hostname
-	}
+	log.Hostname = localHost().name
 	return log
 }
 
@@ -326,9 +463,9 @@ func (log *TransactionLog) GetAccount() *TransactionLog {
 }
 
 func (log *TransactionLog) GetResponseAccount() *TransactionLog {
-	if responsePayload := log.ctx.GetResponsePayload(); responsePayload != nil {
+	if responsePayload := log.crossedResponsePayload(); responsePayload != nil {
 		keys := []string{"phone", "phone_num", "accnbr", "receive_phone"}
-		responseAccountNum := FuzzyGetMany(CrossJson(responsePayload), keys)
+		responseAccountNum := FuzzyGetMany(responsePayload, keys)
 		if responseAccountNum != "" {
 			log.ResponseAccountType = "11"
 			log.ResponseAccountNum = responseAccountNum
@@ -349,6 +486,14 @@ func (log *TransactionLog) GetServiceLine() *TransactionLog {
 	return log
 }
 
+// GetSecurityEvent 获取安全过滤命中的规则，未命中时为空。
+func (log *TransactionLog) GetSecurityEvent() *TransactionLog {
+	if events := log.ctx.getSecurityEvents(); len(events) > 0 {
+		log.SecurityEvent, _ = marshalNoEscape(events)
+	}
+	return log
+}
+
 func deferRecover() {
 	if err := recover(); err != nil {
 		fmt.Printf("An error occurred while executing the transaction log middleware：%v\n", err)
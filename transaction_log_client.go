@@ -0,0 +1,196 @@
+package ginqq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransactionLogTripper 外部流水中间件，为每次出站请求记录 dialog_type 为 "out" 的流水日志，
+// 通过 trace_id、parent_transaction_id 与入站请求的流水关联。
+// 流水写入入站请求所属实例，未关联入站请求时写入 engine，所属实例未开启外部流水时不记录。
+type TransactionLogTripper struct {
+	next   http.RoundTripper
+	engine *GinQQ
+}
+
+func NewTransactionLogTripper() *TransactionLogTripper {
+	return &TransactionLogTripper{}
+}
+
+func (l *TransactionLogTripper) SetNext(next http.RoundTripper) {
+	l.next = next
+}
+
+// RoundTrip 发出请求并在响应体读取完毕或关闭时写入流水，请求体、响应体边读写边记录，
+// 最多保留 RequestCaptureLimit、ResponseCaptureLimit 字节，total_time 包含读取响应体的时间。
+// 响应体未读完也未关闭时，在请求的 context 结束时写入流水并记录 context 的错误；
+// context 不会结束（如 context.Background()）时流水不会写入，调用方需按 net/http 的要求关闭响应体。
+func (l *TransactionLogTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	engine := outboundEngine(req, l.engine)
+	if engine == nil || engine.transactionLogWriter == nil || !engine.Config.outboundTransactionLogEnabled() {
+		return l.next.RoundTrip(req)
+	}
+	log := &TransactionLog{engine: engine, skipPayload: !engine.logControl.capturePayload(req.Header.Get(XMethodCode))}
+
+	var request *outboundRequestCapture
+	if !log.skipPayload && req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(req.Context())
+		request = &outboundRequestCapture{requestCapture: newRequestCapture(req, engine.capture)}
+		req.Body = request
+	}
+
+	log.requestTime = time.Now()
+	resp, err := l.next.RoundTrip(req)
+
+	o := &outboundLog{log: log, req: req, request: request, resp: resp}
+	if err != nil || resp.Body == nil || resp.Body == http.NoBody {
+		o.write(err)
+		return resp, err
+	}
+	o.response = responseCapture{header: resp.Header, opts: engine.capture}
+	o.ReadCloser = resp.Body
+	ctx := req.Context()
+	o.stop = context.AfterFunc(ctx, func() { o.write(ctx.Err()) })
+	resp.Body = o
+	return resp, nil
+}
+
+// outboundRequestCapture 出站请求体可能由 Transport 在其他 goroutine 中写出，记录和读取记录时加锁。
+type outboundRequestCapture struct {
+	mu sync.Mutex
+	*requestCapture
+}
+
+func (r *outboundRequestCapture) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.mu.Lock()
+	r.record(p[:n], err)
+	r.mu.Unlock()
+	return n, err
+}
+
+func (r *outboundRequestCapture) payload(req *http.Request) map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requestCapture.payload(req)
+}
+
+// outboundLog 包装出站响应体，读取的同时保留响应体副本，读到结尾、读取出错、关闭或请求 context 结束时写入一次流水。
+type outboundLog struct {
+	io.ReadCloser
+
+	log      *TransactionLog
+	req      *http.Request
+	request  *outboundRequestCapture
+	resp     *http.Response
+	mu       sync.Mutex // 请求 context 结束时在其他 goroutine 中写入流水，保护 response
+	response responseCapture
+	stop     func() bool // 取消 context 结束时写入流水，只在读取响应体的 goroutine 中调用
+	once     sync.Once
+}
+
+func (o *outboundLog) Read(p []byte) (int, error) {
+	n, err := o.ReadCloser.Read(p)
+	o.mu.Lock()
+	o.response.capture(p[:n])
+	o.mu.Unlock()
+	if err != nil {
+		o.stop()
+		if err == io.EOF {
+			o.write(nil)
+		} else {
+			o.write(err)
+		}
+	}
+	return n, err
+}
+
+func (o *outboundLog) Close() error {
+	err := o.ReadCloser.Close()
+	o.stop()
+	o.write(nil)
+	return err
+}
+
+func (o *outboundLog) write(err error) {
+	o.once.Do(func() {
+		o.log.responseTime = time.Now()
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		engine := o.log.engine
+		o.log.outbound(o.req, o.request, o.resp, &o.response, err)
+		o.log.mask()
+		if msg, err := json.Marshal(o.log); err == nil {
+			engine.transactionLogWriter.Write(msg)
+		}
+	})
+}
+
+// outbound 填充外部流水字段，err 为请求失败或读取响应体出错的原因。
+func (log *TransactionLog) outbound(req *http.Request, request *outboundRequestCapture, resp *http.Response, response *responseCapture, err error) {
+	defer deferRecover()
+
+	log.GetAppName().GetLevel().GetLogTime().GetLogger().GetThread().
+		GetRequestTime().GetResponseTime().GetTotalTime().GetHostIP().GetHostname()
+
+	log.DialogType = "out"
+	log.TransactionID = req.Header.Get(XTransactionID)
+	log.TraceID = req.Header.Get(XTraceID)
+	if values := outboundValuesFromContext(req.Context()); values != nil {
+		log.ParentTransactionID = values.transactionID
+		if log.TraceID == "" {
+			log.TraceID = values.traceID
+		}
+	}
+
+	log.Address = fmt.Sprintf("%s://%s%s", req.URL.Scheme, req.URL.Host, req.URL.Path)
+	log.FCode = strings.ToUpper(req.Header.Get(XFCode))
+	log.TCode = tCodeFromContext(req.Context())
+	log.MethodCode = req.Header.Get(XMethodCode)
+	log.HTTPMethod = req.Method
+	log.RequestHeaders = serializeHeaders(req.Header)
+	var requestPayload map[string]interface{}
+	if request != nil {
+		requestPayload = request.payload(req)
+	} else {
+		requestPayload = make(map[string]interface{})
+		mergeValues(requestPayload, req.URL.Query())
+	}
+	log.RequestPayload, _ = marshalNoEscape(requestPayload)
+
+	if err != nil {
+		log.ErrorCode = classifyTransportError(err)
+		log.ResponseRemark = err.Error()
+	}
+	if resp == nil {
+		log.ResponsePayload = "{}"
+		return
+	}
+
+	log.HTTPStatusCode = strconv.Itoa(resp.StatusCode)
+	log.ResponseHeaders = serializeHeaders(resp.Header)
+	if response == nil || log.skipPayload {
+		log.ResponsePayload = "{}"
+	} else {
+		log.ResponsePayload = response.payload()
+	}
+	if response != nil {
+		log.ResponseCode = FuzzyGet(response.data(), "code")
+	}
+}
+
+func serializeHeaders(header http.Header) string {
+	headers := make(map[string]string)
+	for k, v := range header {
+		headers[k] = strings.Join(v, ", ")
+	}
+	headersSerialized, _ := json.Marshal(headers)
+	return string(headersSerialized)
+}
@@ -0,0 +1,459 @@
+package ginqq
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	lumberjack "github.com/DeRuina/timberjack"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TransactionLogSink 流水日志输出目标，record 为一条序列化后的流水（不含换行符）。
+// Write 由流水写入协程并发调用，实现需保证并发安全。
+// 实现 Dropped() uint64 的输出，其自身丢弃的流水计入 GinQQ.TransactionLogDropped 及丢弃数指标。
+type TransactionLogSink interface {
+	Write(record []byte) error
+	Close() error
+}
+
+// FileSink 按 LogConfig 轮转的日志文件。
+type FileSink struct {
+	logger *lumberjack.Logger
+}
+
+// NewFileSink 创建写入 filename 的日志文件输出，轮转策略使用 LogConfig 。
+func NewFileSink(cfg *LogConfig, filename string) *FileSink {
+	return &FileSink{logger: &lumberjack.Logger{
+		Filename:         filename,
+		MaxSize:          cfg.MaxSize,
+		MaxAge:           cfg.MaxAge,
+		MaxBackups:       cfg.MaxBackups,
+		LocalTime:        cfg.LocalTime,
+		Compress:         cfg.Compress,
+		RotationInterval: cfg.RotationInterval,
+	}}
+}
+
+func (s *FileSink) Write(record []byte) error {
+	_, err := s.logger.Write(appendNewline(record))
+	return err
+}
+
+func (s *FileSink) Close() error {
+	return s.logger.Close()
+}
+
+// WriterSink 写入任意 io.Writer，如标准输出（供 Kubernetes 日志采集）。
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// NewStdoutSink 输出到标准输出。
+func NewStdoutSink() *WriterSink {
+	return NewWriterSink(os.Stdout)
+}
+
+// NewStderrSink 输出到标准错误。
+func NewStderrSink() *WriterSink {
+	return NewWriterSink(os.Stderr)
+}
+
+func (s *WriterSink) Write(record []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.w.Write(appendNewline(record))
+	return err
+}
+
+func (s *WriterSink) Close() error {
+	return nil
+}
+
+// syslogTimeout syslog 连接及单条写入的超时时间。
+const syslogTimeout = 5 * time.Second
+
+// SyslogSink 以 RFC 5424 格式发送到 syslog 服务，支持 unix、unixgram、udp、tcp，
+// tcp 使用 RFC 6587 八位组计数分帧，连接断开后在下次写入时重连。
+type SyslogSink struct {
+	network string
+	addr    string
+	tag     string
+	host    string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSink 创建 syslog 输出，tag 为 APP-NAME，如 "a186010101_app_info"。
+func NewSyslogSink(network, addr, tag string) (*SyslogSink, error) {
+	switch network {
+	case "unix", "unixgram", "udp", "tcp":
+	default:
+		return nil, fmt.Errorf("syslog sink: unsupported network %q", network)
+	}
+	s := &SyslogSink{network: network, addr: addr, tag: tag, host: localHost().name}
+	if s.host == "" {
+		s.host = "-"
+	}
+	if s.tag == "" {
+		s.tag = "-"
+	}
+	return s, nil
+}
+
+func (s *SyslogSink) Write(record []byte) error {
+	// PRI = facility local0(16) * 8 + severity info(6)
+	msg := fmt.Sprintf("<134>1 %s %s %s %d - - %s",
+		time.Now().Format(time.RFC3339Nano), s.host, s.tag, os.Getpid(), record)
+	if s.network == "tcp" {
+		msg = strconv.Itoa(len(msg)) + " " + msg
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 对端关闭后首次写入通常仍会成功而数据丢失，写入前先探测流式连接是否已断开
+	if s.conn != nil && s.network != "udp" && s.network != "unixgram" && connClosed(s.conn) {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			conn, err := net.DialTimeout(s.network, s.addr, syslogTimeout)
+			if err != nil {
+				return err
+			}
+			s.conn = conn
+		}
+		_ = s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+		if _, err := io.WriteString(s.conn, msg); err != nil {
+			_ = s.conn.Close()
+			s.conn = nil
+			if attempt == 1 {
+				return err
+			}
+			continue
+		}
+		return nil
+	}
+	return nil
+}
+
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// HTTPBatchSinkOptions HTTPBatchSink 配置。
+type HTTPBatchSinkOptions struct {
+	BatchSize     int               // 单批最大条数，默认 100
+	FlushInterval time.Duration     // 未满一批时的发送间隔，默认 5 秒
+	QueueSize     int               // 待发送队列长度，队列满时丢弃，默认 10000
+	Headers       map[string]string // 附加请求头，如鉴权
+	Client        *http.Client      // 默认使用未经 HttpEnhance 增强的传输层，超时 10 秒
+}
+
+// HTTPBatchSink 将流水批量 POST 到日志收集服务，请求体为 NDJSON（每行一条流水）。
+// 发送在独立协程中进行，不阻塞流水写入，发送失败的批次丢弃。
+type HTTPBatchSink struct {
+	url     string
+	opts    HTTPBatchSinkOptions
+	queue   chan []byte
+	dropped atomic.Uint64
+
+	flushReq chan chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+func NewHTTPBatchSink(url string, opts HTTPBatchSinkOptions) *HTTPBatchSink {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 5 * time.Second
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 10000
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Transport: originalDefaultTransport, Timeout: 10 * time.Second}
+	}
+	s := &HTTPBatchSink{
+		url:      url,
+		opts:     opts,
+		queue:    make(chan []byte, opts.QueueSize),
+		flushReq: make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *HTTPBatchSink) Write(record []byte) error {
+	select {
+	case s.queue <- bytes.Clone(record):
+		return nil
+	default:
+		s.dropped.Add(1)
+		return errors.New("http batch sink: queue is full")
+	}
+}
+
+// Dropped 返回因队列已满而丢弃的流水数。
+func (s *HTTPBatchSink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Flush 立即发送队列中的流水。
+func (s *HTTPBatchSink) Flush() {
+	done := make(chan struct{})
+	select {
+	case s.flushReq <- done:
+		<-done
+	case <-s.done:
+	}
+}
+
+// Close 发送剩余流水后停止。
+func (s *HTTPBatchSink) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	<-s.done
+	return nil
+}
+
+func (s *HTTPBatchSink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	var batch [][]byte
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.post(batch); err != nil {
+			fmt.Printf("[TransactionLog] http batch sink: send %d records failed: %v\n", len(batch), err)
+		}
+		batch = nil
+	}
+	drain := func() {
+		for {
+			select {
+			case record := <-s.queue:
+				batch = append(batch, record)
+				if len(batch) >= s.opts.BatchSize {
+					send()
+				}
+			default:
+				send()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case record := <-s.queue:
+			batch = append(batch, record)
+			if len(batch) >= s.opts.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case done := <-s.flushReq:
+			drain()
+			close(done)
+		case <-s.stop:
+			drain()
+			return
+		}
+	}
+}
+
+func (s *HTTPBatchSink) post(batch [][]byte) error {
+	var body bytes.Buffer
+	for _, record := range batch {
+		body.Write(record)
+		body.WriteByte('\n')
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.Client.Timeout+time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for k, v := range s.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// multiSink 将流水写入多个输出，每个输出使用独立的有界队列和写入协程，
+// 单个输出失败或 panic 不影响其他输出。输出队列已满时按 OverflowPolicy 处理：
+// OverflowBlock 等待该输出的队列空闲，保证流水不丢失（缓慢的输出会拖慢整体写入），其他策略只丢弃该输出的流水。
+// 错误按输出分别计数，每个输出每 10 秒最多打印一次错误。
+type multiSink struct {
+	sinks  []*isolatedSink
+	policy OverflowPolicy
+}
+
+type isolatedSink struct {
+	index    int
+	sink     TransactionLogSink
+	queue    chan []byte
+	done     chan struct{}
+	dropped  atomic.Uint64
+	errors   atomic.Uint64
+	reported atomic.Int64 // 上次打印错误的时间（UnixNano）
+}
+
+func newMultiSink(queueSize int, policy OverflowPolicy, sinks ...TransactionLogSink) *multiSink {
+	m := &multiSink{policy: policy}
+	for i, sink := range sinks {
+		s := &isolatedSink{index: i, sink: sink, queue: make(chan []byte, queueSize), done: make(chan struct{})}
+		go s.run()
+		m.sinks = append(m.sinks, s)
+	}
+	return m
+}
+
+// Write 将流水提交到每个输出的队列，返回队列已满而丢弃流水的输出的错误。
+func (m *multiSink) Write(record []byte) error {
+	var errs []error
+	for _, s := range m.sinks {
+		if err := s.enqueue(record, m.policy); err != nil {
+			s.report(err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Dropped 返回各输出因队列已满而丢弃的流水总数，包括输出自身丢弃的流水（输出实现 Dropped() uint64 时，如 HTTPBatchSink）。
+func (m *multiSink) Dropped() uint64 {
+	var dropped uint64
+	for _, s := range m.sinks {
+		dropped += s.dropped.Load()
+		if d, ok := s.sink.(interface{ Dropped() uint64 }); ok {
+			dropped += d.Dropped()
+		}
+	}
+	return dropped
+}
+
+// Close 等待各输出写完队列中的流水后关闭输出，Close 后不可再调用 Write 。
+func (m *multiSink) Close() error {
+	for _, s := range m.sinks {
+		close(s.queue)
+	}
+	var errs []error
+	for _, s := range m.sinks {
+		<-s.done
+		if err := s.sink.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close sink %d (%T): %w", s.index, s.sink, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// enqueue 将流水放入队列，队列已满时按 policy 处理，丢弃流水时返回错误。
+func (s *isolatedSink) enqueue(record []byte, policy OverflowPolicy) error {
+	switch policy {
+	case OverflowDropNewest:
+		select {
+		case s.queue <- record:
+			return nil
+		default:
+		}
+	case OverflowDropOldest:
+		var err error
+		for {
+			select {
+			case s.queue <- record:
+				return err
+			default:
+			}
+			select {
+			case <-s.queue:
+				s.dropped.Add(1)
+				err = fmt.Errorf("sink %d (%T): queue is full, oldest record dropped", s.index, s.sink)
+			default:
+			}
+		}
+	default:
+		s.queue <- record
+		return nil
+	}
+	s.dropped.Add(1)
+	return fmt.Errorf("sink %d (%T): queue is full", s.index, s.sink)
+}
+
+func (s *isolatedSink) run() {
+	defer close(s.done)
+	for record := range s.queue {
+		_ = s.write(record)
+	}
+}
+
+func (s *isolatedSink) write(record []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			debug.PrintStack()
+		}
+		if err != nil {
+			err = fmt.Errorf("sink %d (%T): %w", s.index, s.sink, err)
+			s.report(err)
+		}
+	}()
+	return s.sink.Write(record)
+}
+
+func (s *isolatedSink) report(err error) {
+	total := s.errors.Add(1)
+	now := time.Now().UnixNano()
+	last := s.reported.Load()
+	if now-last < int64(10*time.Second) || !s.reported.CompareAndSwap(last, now) {
+		return
+	}
+	fmt.Printf("[TransactionLog] write failed (%d errors so far): %v\n", total, err)
+}
+
+func appendNewline(record []byte) []byte {
+	line := make([]byte, 0, len(record)+1)
+	line = append(line, record...)
+	return append(line, '\n')
+}
@@ -0,0 +1,185 @@
+package ginqq
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type failingSink struct{ panics bool }
+
+func (s failingSink) Write([]byte) error {
+	if s.panics {
+		panic("sink is broken")
+	}
+	return errors.New("disk full")
+}
+
+func (s failingSink) Close() error { return nil }
+
+// droppingSink 丢弃全部流水并计数，模拟自带队列的输出。
+type droppingSink struct{ dropped atomic.Uint64 }
+
+func (s *droppingSink) Write([]byte) error { s.dropped.Add(1); return nil }
+func (s *droppingSink) Close() error       { return nil }
+func (s *droppingSink) Dropped() uint64    { return s.dropped.Load() }
+
+func TestMultiSinkIsolation(t *testing.T) {
+	var buf bytes.Buffer
+	sink := newMultiSink(8, OverflowBlock, failingSink{}, failingSink{panics: true}, NewWriterSink(&buf))
+
+	if err := sink.Write([]byte(`{"a":1}`)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "{\"a\":1}\n" {
+		t.Errorf("healthy sink got %q", buf.String())
+	}
+	if sink.sinks[0].errors.Load() != 1 || sink.sinks[1].errors.Load() != 1 || sink.sinks[2].errors.Load() != 0 {
+		t.Errorf("unexpected per-sink error counts")
+	}
+
+	// 阻塞的输出只丢弃自己的流水，不影响其他输出
+	unblock := make(chan struct{})
+	lines := make(chan []byte, 4)
+	sink = newMultiSink(1, OverflowDropNewest, sinkFunc(func([]byte) { <-unblock }), sinkFunc(func(msg []byte) { lines <- msg }))
+	for i := 0; i < 3; i++ {
+		_ = sink.Write([]byte(`{"a":1}`))
+		<-lines
+	}
+	if sink.sinks[0].dropped.Load() == 0 || sink.sinks[1].dropped.Load() != 0 {
+		t.Errorf("dropped = %d, %d", sink.sinks[0].dropped.Load(), sink.sinks[1].dropped.Load())
+	}
+	close(unblock)
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMultiSinkOverflowBlock(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		written int
+	)
+	sink := newMultiSink(1, OverflowBlock, sinkFunc(func([]byte) {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		written++
+		mu.Unlock()
+	}))
+	for i := 0; i < 20; i++ {
+		if err := sink.Write([]byte(`{"a":1}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if written != 20 || sink.Dropped() != 0 {
+		t.Errorf("written = %d, dropped = %d", written, sink.Dropped())
+	}
+
+	// 输出自身丢弃的流水计入丢弃数
+	sink = newMultiSink(1, OverflowBlock, &droppingSink{})
+	for i := 0; i < 3; i++ {
+		_ = sink.Write([]byte(`{"a":1}`))
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if sink.Dropped() != 3 {
+		t.Errorf("dropped = %d, want sink-internal drops counted", sink.Dropped())
+	}
+}
+
+func TestHTTPBatchSink(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "application/x-ndjson" || r.Header.Get("X-Api-Key") != "k" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		batches = append(batches, strings.Split(strings.TrimSuffix(string(body), "\n"), "\n"))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	sink := NewHTTPBatchSink(server.URL, HTTPBatchSinkOptions{
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		Headers:       map[string]string{"X-Api-Key": "k"},
+	})
+	for _, record := range []string{`{"n":1}`, `{"n":2}`, `{"n":3}`} {
+		if err := sink.Write([]byte(record)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batches) != 2 || len(batches[0]) != 2 || batches[1][0] != `{"n":3}` {
+		t.Errorf("unexpected batches: %v", batches)
+	}
+}
+
+func TestSyslogSink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 2)
+	go func() {
+		for i := 0; i < 2; i++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			line, _ := bufio.NewReader(conn).ReadString('}')
+			conn.Close() // 模拟服务端断开，下一条流水需重连
+			received <- line
+		}
+	}()
+
+	sink, err := NewSyslogSink("tcp", listener.Addr().String(), "a186010101_app_info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	// 每条流水只写入一次，服务端断开后的下一条流水不丢失
+	for _, record := range []string{`{"n":1}`, `{"n":2}`} {
+		if err := sink.Write([]byte(record)); err != nil {
+			t.Fatal(err)
+		}
+		var msg string
+		select {
+		case msg = <-received:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("record %s not received", record)
+		}
+		if !strings.Contains(msg, " <134>1 ") || !strings.Contains(msg, " a186010101_app_info ") || !strings.HasSuffix(msg, record) {
+			t.Errorf("unexpected syslog message %q", msg)
+		}
+	}
+}
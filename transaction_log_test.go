@@ -0,0 +1,235 @@
+package ginqq
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func newTransactionLogTestEngine(tb testing.TB, cfg *TransactionLogConfig, write func([]byte)) *gin.Engine {
+	engine := newTestGinQQ(tb, &Config{TransactionLogConfig: cfg}, write).Engine
+	engine.Use(convertToGinHandlers([]func(*Context){DispatchTransactionLog})...)
+	engine.POST("/users/:id", convertToGinHandlers([]func(*Context){MethodCode("I00101"), func(c *Context) {
+		c.JSON(http.StatusOK, H{"code": "0000", "data": H{"phone": "13800001234", "province_code": "44"}})
+	}})...)
+	return engine
+}
+
+func newTransactionLogTestRequest() *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/users/1?x=1", strings.NewReader(`{"name":"ginqq","phone":"13800001234"}`))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestDispatchTransactionLog(t *testing.T) {
+	lines := make(chan []byte, 1)
+	engine := newTransactionLogTestEngine(t, nil, func(msg []byte) { lines <- msg })
+	engine.ServeHTTP(httptest.NewRecorder(), newTransactionLogTestRequest())
+
+	var log map[string]interface{}
+	if err := json.Unmarshal(<-lines, &log); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]interface{}{
+		"dialog_type":      "in",
+		"method_code":      "I00101",
+		"http_method":      "POST",
+		"request_payload":  `{"name":"ginqq","phone":"138****1234","x":"1"}`,
+		"response_code":    "0000",
+		"http_status_code": "200",
+		"province_code":    "44",
+		"account_num":      "138****1234",
+	} {
+		if got := log[key]; got != want {
+			t.Errorf("%s = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestTransactionLogFields(t *testing.T) {
+	lines := make(chan []byte, 1)
+	tagged := newTransactionLogTestEngine(t, &TransactionLogConfig{Fields: []TransactionLogField{
+		{Name: "Tag", Extract: func(log *TransactionLog) { log.Tag = log.Context().GetHeader("X-Tag") }},
+	}}, func(msg []byte) { lines <- msg })
+	plain := newTransactionLogTestEngine(t, nil, func(msg []byte) { lines <- msg })
+
+	// 自定义提取器只对配置的实例生效
+	for engine, want := range map[*gin.Engine]string{tagged: "vip", plain: ""} {
+		req := newTransactionLogTestRequest()
+		req.Header.Set("X-Tag", "vip")
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+		var log map[string]interface{}
+		if err := json.Unmarshal(<-lines, &log); err != nil {
+			t.Fatal(err)
+		}
+		if log["tag"] != want || log["method_code"] != "I00101" {
+			t.Errorf("tag = %v, want %q", log["tag"], want)
+		}
+	}
+}
+
+func TestAsyncWriterOverflow(t *testing.T) {
+	for _, tt := range []struct {
+		policy  OverflowPolicy
+		written []string
+	}{
+		{OverflowDropNewest, []string{"1", "2"}},
+		{OverflowDropOldest, []string{"3", "4"}},
+	} {
+		release := make(chan struct{})
+		var written []string
+		// 写入协程阻塞在第一条日志上，队列容量为 2
+		w := newAsyncWriter(func(msg []byte) {
+			if string(msg) == "0" {
+				<-release
+				return
+			}
+			written = append(written, string(msg))
+		}, 2, 1, tt.policy)
+
+		w.Write([]byte("0"))
+		for len(w.queue) != 0 { // 等待写入协程取走第一条日志
+			runtime.Gosched()
+		}
+		for _, msg := range []string{"1", "2", "3", "4"} {
+			w.Write([]byte(msg))
+		}
+		close(release)
+		w.Close()
+
+		if strings.Join(written, ",") != strings.Join(tt.written, ",") {
+			t.Errorf("%s: written %v, want %v", tt.policy, written, tt.written)
+		}
+		if w.Dropped() != 2 {
+			t.Errorf("%s: dropped %d, want 2", tt.policy, w.Dropped())
+		}
+	}
+}
+
+// BenchmarkDispatchTransactionLog 流水日志中间件开销。
+func BenchmarkDispatchTransactionLog(b *testing.B) {
+	engine := newTransactionLogTestEngine(b, nil, func([]byte) {})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		engine.ServeHTTP(httptest.NewRecorder(), newTransactionLogTestRequest())
+	}
+}
+
+func TestTransactionLogResponseCapture(t *testing.T) {
+	lines := make(chan []byte, 1)
+	engine := newTransactionLogTestEngine(t, &TransactionLogConfig{ResponseCaptureLimit: 32}, func(msg []byte) { lines <- msg })
+
+	tests := []struct {
+		name            string
+		handler         func(*Context)
+		responsePayload string
+		responseCode    string
+	}{
+		{"string", func(c *Context) { c.String(http.StatusOK, "hello %s", "ginqq") }, `"hello ginqq"`, ""},
+		{"data json", func(c *Context) {
+			c.Data(http.StatusOK, "application/json", []byte(`{ "code": "0001" }`))
+		}, `{"code":"0001"}`, "0001"},
+		{"xml", func(c *Context) { c.XML(http.StatusOK, gin.H{"code": "0002"}) }, `"<map><code>0002</code></map>"`, "0002"},
+		{"yaml", func(c *Context) { c.YAML(http.StatusOK, gin.H{"code": "0003"}) }, `"code: \"0003\"\n"`, "0003"},
+		{"truncated json", func(c *Context) {
+			c.Data(http.StatusOK, "application/json", []byte(`{"code":"0004","data":"`+strings.Repeat("a", 40)+`"}`))
+		}, `"{\"code\":\"0004\",\"data\":\"aaaaaaaaa...[truncated, 65 bytes total]"`, "0004"},
+		{"truncated xml", func(c *Context) {
+			c.Data(http.StatusOK, "application/xml", []byte(`<r><code>0005</code><data>`+strings.Repeat("a", 40)+`</data></r>`))
+		}, `"<r><code>0005</code><data>aaaaaa...[truncated, 77 bytes total]"`, "0005"},
+		{"truncated", func(c *Context) {
+			c.String(http.StatusOK, strings.Repeat("a", 40))
+		}, `"` + strings.Repeat("a", 32) + `...[truncated, 40 bytes total]"`, ""},
+		{"context json", func(c *Context) {
+			c.JSON(http.StatusOK, gin.H{"code": "0006", "data": "<a&b>"})
+		}, `{"code":"0006","data":"<a&b>"}`, "0006"},
+		{"truncated context json", func(c *Context) {
+			c.JSON(http.StatusOK, gin.H{"code": "0007", "data": strings.Repeat("a", 40)})
+		}, `"{\"code\":\"0007\",\"data\":\"aaaaaaaaa...[truncated, 65 bytes total]"`, "0007"},
+		{"writer binary", func(c *Context) {
+			c.Header("Content-Type", "application/octet-stream")
+			_, _ = c.Writer.Write(make([]byte, 100))
+		}, `"[binary content omitted, content-type: application/octet-stream, 100 bytes]"`, ""},
+		{"empty", func(c *Context) { c.Status(http.StatusNoContent) }, `{}`, ""},
+	}
+	for i, tt := range tests {
+		path := "/capture/" + strconv.Itoa(i)
+		engine.GET(path, convertToGinHandlers([]func(*Context){tt.handler})...)
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+
+		var log map[string]interface{}
+		if err := json.Unmarshal(<-lines, &log); err != nil {
+			t.Fatal(err)
+		}
+		if log["response_payload"] != tt.responsePayload {
+			t.Errorf("%s: response_payload = %v, want %v", tt.name, log["response_payload"], tt.responsePayload)
+		}
+		if log["response_code"] != tt.responseCode {
+			t.Errorf("%s: response_code = %v, want %v", tt.name, log["response_code"], tt.responseCode)
+		}
+	}
+}
+
+func TestTransactionLogRequestCapture(t *testing.T) {
+	lines := make(chan []byte, 1)
+	engine := newTransactionLogTestEngine(t, &TransactionLogConfig{RequestCaptureLimit: 32}, func(msg []byte) { lines <- msg })
+
+	var received int
+	engine.POST("/read", convertToGinHandlers([]func(*Context){func(c *Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		received = len(body)
+	}})...)
+	engine.POST("/ignore", convertToGinHandlers([]func(*Context){func(c *Context) {}})...)
+	engine.POST("/upload", convertToGinHandlers([]func(*Context){func(c *Context) {
+		_, _ = c.FormFile("file")
+	}})...)
+
+	var multipartBody bytes.Buffer
+	mw := multipart.NewWriter(&multipartBody)
+	_ = mw.WriteField("name", "ginqq")
+	fw, _ := mw.CreateFormFile("file", "avatar.png")
+	_, _ = fw.Write(make([]byte, 1000))
+	_ = mw.Close()
+
+	tests := []struct {
+		name, path, contentType, body, requestPayload string
+	}{
+		{"truncated json", "/read", "application/json", `{"data":"` + strings.Repeat("a", 100) + `"}`,
+			`{"_body":"{\"data\":\"` + strings.Repeat("a", 23) + `...[truncated, 111 bytes total]"}`},
+		{"unread json", "/ignore", "application/json", `{"name":"ginqq"}`, `{"name":"ginqq"}`},
+		{"form", "/ignore", "application/x-www-form-urlencoded", `name=ginqq&tag=a&tag=b`, `{"name":"ginqq","tag":["a","b"]}`},
+		{"binary", "/read", "application/octet-stream", strings.Repeat("\x00", 500),
+			`{"_body":"[binary content omitted, content-type: application/octet-stream, 500 bytes]"}`},
+		{"multipart", "/upload", mw.FormDataContentType(), multipartBody.String(),
+			`{"_files":[{"content_type":"application/octet-stream","field":"file","filename":"avatar.png","size":1000}],"name":"ginqq"}`},
+		{"multipart unparsed", "/ignore", mw.FormDataContentType(), multipartBody.String(),
+			`{"_body":"[multipart body not parsed by handler, ` + strconv.Itoa(multipartBody.Len()) + ` bytes]"}`},
+	}
+	for _, tt := range tests {
+		received = 0
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		engine.ServeHTTP(httptest.NewRecorder(), req)
+
+		var log map[string]interface{}
+		if err := json.Unmarshal(<-lines, &log); err != nil {
+			t.Fatal(err)
+		}
+		if log["request_payload"] != tt.requestPayload {
+			t.Errorf("%s: request_payload = %v, want %v", tt.name, log["request_payload"], tt.requestPayload)
+		}
+		if tt.path == "/read" && received != len(tt.body) {
+			t.Errorf("%s: handler received %d bytes, want %d", tt.name, received, len(tt.body))
+		}
+	}
+}
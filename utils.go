@@ -1,13 +1,16 @@
 package ginqq
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	uuid "github.com/satori/go.uuid"
 	"net"
+	"os"
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 )
 
 func uuid4() string {
@@ -109,12 +112,42 @@ func simplifyKey(key string) string {
 	return strings.ToLower(key)
 }
 
+// marshalNoEscape 序列化为 JSON，不转义 HTML 字符（<、>、&）。
+func marshalNoEscape(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
 func CrossJson(data interface{}) (deserialization interface{}) {
 	serialized, _ := json.Marshal(data)
 	_ = json.Unmarshal(serialized, &deserialization)
 	return deserialization
 }
 
+type hostInfo struct {
+	ip   string
+	name string
+}
+
+var (
+	host     hostInfo
+	hostOnce sync.Once
+)
+
+// localHost 返回本机 IP 和主机名，首次调用时获取并缓存。
+func localHost() hostInfo {
+	hostOnce.Do(func() {
+		host.ip, _ = GetHostIP()
+		host.name, _ = os.Hostname()
+	})
+	return host
+}
+
 // GetHostIP 获取主机的非回环 IPv4 地址。
 func GetHostIP() (string, error) {
 	interfaces, err := net.Interfaces()